@@ -0,0 +1,181 @@
+package kandinsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrServiceDisabled is returned when Kandinsky API reports that generation queue
+// is disabled or overloaded, use errors.As with *UnavailableError to get status.
+var ErrServiceDisabled = errors.New("kandinsky service is disabled, generation queue is overloaded")
+
+// Service statuses
+const (
+	// Service accepts generation tasks
+	ACTIVE = "ACTIVE"
+	// Generation queue is disabled or overloaded
+	DISABLED_BY_QUEUE = "DISABLED_BY_QUEUE"
+)
+
+// Availability is the message from Kandinsky API about service status
+//
+//	{
+//		"model_status": "DISABLED_BY_QUEUE"
+//	}
+type Availability struct {
+	// Status of the service, e.g. "ACTIVE".
+	Status string `json:"status"`
+	// Status of the model, "DISABLED_BY_QUEUE" when queue is overloaded.
+	ModelStatus string `json:"model_status"`
+}
+
+// Available reports whether service accepts generation tasks.
+func (a *Availability) Available() bool {
+	return a.Status != DISABLED_BY_QUEUE && a.ModelStatus != DISABLED_BY_QUEUE
+}
+
+// status returns the most specific status reported
+func (a *Availability) status() string {
+	if a.ModelStatus != "" {
+		return a.ModelStatus
+	}
+
+	return a.Status
+}
+
+// UnavailableError is returned when service reports it is unavailable.
+type UnavailableError struct {
+	// Status reported by Kandinsky API, e.g. "DISABLED_BY_QUEUE".
+	Status string
+	// Number of availability requests done, 0 if status came from run endpoint.
+	Attempts int
+	// Time spent waiting for service.
+	Elapsed time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: status %s after %d attempts in %s", ErrServiceDisabled, e.Status, e.Attempts, e.Elapsed)
+}
+
+// Is makes errors.Is(err, ErrServiceDisabled) true
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrServiceDisabled
+}
+
+// AvailabilityMode defines what GetImage does before submitting generation task.
+type AvailabilityMode int
+
+const (
+	// Submit task without availability check
+	AvailabilityIgnore AvailabilityMode = iota
+	// Fail with *UnavailableError if service is unavailable
+	AvailabilityFailFast
+	// Wait until service is available following poll policy
+	AvailabilityWait
+)
+
+// WithAvailabilityCheck makes GetImage check service availability before submitting task.
+// In AvailabilityWait mode service is polled following p, zero p means DefaultPollPolicy.
+func WithAvailabilityCheck(mode AvailabilityMode, p PollPolicy) Option {
+	return func(k *Kand) {
+		k.availMode = mode
+		k.availPoll = p
+	}
+}
+
+// Availability returns service status for current model, model is set if not set yet.
+//
+//	{
+//		"model_status": "DISABLED_BY_QUEUE"
+//	}
+func (k *Kand) Availability() (*Availability, error) {
+	return k.AvailabilityContext(context.Background())
+}
+
+// AvailabilityContext is like Availability but uses ctx for the request.
+func (k *Kand) AvailabilityContext(ctx context.Context) (*Availability, error) {
+	a := new(Availability)
+
+	m, err := k.ensureModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := k.availURL + "?model_id=" + strconv.Itoa(m.ID)
+
+	// do GET request with auth headers to Kandinsky API
+	ctx = withEndpoint(ctx, EndpointAvailability)
+	res, err := k.do(ctx, func() (*http.Request, error) {
+		return k.newRequest(ctx, http.MethodGet, url, nil)
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// checkAvailability checks service before submitting task following availability mode
+func (k *Kand) checkAvailability(ctx context.Context) error {
+	if k.availMode == AvailabilityIgnore {
+		return nil
+	}
+
+	p := k.availPoll.withDefaults()
+
+	c := k.getClock()
+	start := c.Now()
+	interval := p.Interval
+
+	for attempt := 1; ; attempt++ {
+		a, err := k.AvailabilityContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		if a.Available() {
+			return nil
+		}
+
+		e := &UnavailableError{Status: a.status(), Attempts: attempt, Elapsed: c.Now().Sub(start)}
+		if k.availMode != AvailabilityWait || (p.MaxAttempts > 0 && attempt >= p.MaxAttempts) {
+			return e
+		}
+
+		// wait, but not longer than overall deadline
+		delay := interval
+		if p.Timeout > 0 {
+			left := p.Timeout - c.Now().Sub(start)
+			if left <= 0 {
+				return e
+			}
+			if delay > left {
+				delay = left
+			}
+		}
+
+		err = sleep(ctx, c, delay)
+		if err != nil {
+			return err
+		}
+
+		interval = p.next(interval)
+	}
+}
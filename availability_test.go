@@ -0,0 +1,146 @@
+package kandinsky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// activeAfter reports service disabled by queue until n-th availability request, 0 means forever
+func activeAfter(after int32) func(n int32) string {
+	return func(n int32) string {
+		if after > 0 && n >= after {
+			return `{"status":"ACTIVE"}`
+		}
+		return `{"model_status":"DISABLED_BY_QUEUE"}`
+	}
+}
+
+// TestAvailability common test
+func TestAvailability(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		activeAfter int32
+		available   bool
+		status      string
+	}{
+		{
+			desc:        "Active",
+			activeAfter: 1,
+			available:   true,
+			status:      ACTIVE,
+		},
+		{
+			desc:        "Disabled by queue",
+			activeAfter: 0,
+			available:   false,
+			status:      DISABLED_BY_QUEUE,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ts := (&fakeAPI{availability: activeAfter(tC.activeAfter)}).start(t)
+
+			k, err := New("key", "secret", WithBaseURL(ts.URL))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			a, err := k.Availability()
+			if err != nil {
+				t.Fatalf("\n%s: unexpected error > %s", tC.desc, err)
+			}
+
+			if a.Available() != tC.available || a.status() != tC.status {
+				t.Errorf("\n%s:\n\twant:\n\t\t%t %s \n\tgot:\n\t\t%t %s\n", tC.desc, tC.available, tC.status, a.Available(), a.status())
+			}
+		})
+	}
+}
+
+// TestGetImageAvailability checks availability modes of GetImage
+func TestGetImageAvailability(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		mode        AvailabilityMode
+		policy      PollPolicy
+		activeAfter int32
+		checks      int32
+		runs        int32
+		want        error
+	}{
+		{
+			desc:        "Ignore submits anyway",
+			mode:        AvailabilityIgnore,
+			activeAfter: 0,
+			checks:      0,
+			runs:        1,
+			want:        nil,
+		},
+		{
+			desc:        "Fail fast",
+			mode:        AvailabilityFailFast,
+			activeAfter: 0,
+			checks:      1,
+			runs:        0,
+			want:        ErrServiceDisabled,
+		},
+		{
+			desc:        "Wait until active",
+			mode:        AvailabilityWait,
+			policy:      PollPolicy{Interval: time.Second},
+			activeAfter: 3,
+			checks:      3,
+			runs:        1,
+			want:        nil,
+		},
+		{
+			desc:        "Wait gives up",
+			mode:        AvailabilityWait,
+			policy:      PollPolicy{Interval: time.Second, MaxAttempts: 2},
+			activeAfter: 0,
+			checks:      2,
+			runs:        0,
+			want:        ErrServiceDisabled,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f := &fakeAPI{availability: activeAfter(tC.activeAfter)}
+			ts := f.start(t)
+			c := &fakeClock{now: time.Unix(0, 0)}
+
+			_, err := GetImage("key", "secret", params, WithBaseURL(ts.URL), WithClock(c), WithAvailabilityCheck(tC.mode, tC.policy))
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			checks, runs := atomic.LoadInt32(&f.availabilities), atomic.LoadInt32(&f.runs)
+			if checks != tC.checks || runs != tC.runs {
+				t.Errorf("\n%s:\n\twant checks and runs:\n\t\t%d %d \n\tgot:\n\t\t%d %d\n", tC.desc, tC.checks, tC.runs, checks, runs)
+			}
+		})
+	}
+}
+
+// TestGetImageUUIDDisabled checks availability status returned by run endpoint
+func TestGetImageUUIDDisabled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model_status":"DISABLED_BY_QUEUE"}`))
+	}))
+	defer ts.Close()
+
+	k := &Kand{key: "key", secret: "secret", genURL: ts.URL, Model: Model{ID: 4}}
+
+	_, err := k.GetImageUUID(params)
+
+	var e *UnavailableError
+	if !errors.As(err, &e) || e.Status != DISABLED_BY_QUEUE {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrServiceDisabled, err)
+	}
+}
@@ -0,0 +1,119 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrBatchFailed is returned by CollectBatch when some items failed,
+// use errors.As with *BatchError to get them.
+var ErrBatchFailed = errors.New("kandinsky some batch items failed")
+
+// DefaultBatchConcurrency is number of parallel generations in batch by default
+const DefaultBatchConcurrency = 4
+
+// BatchOptions configures GenerateBatch.
+type BatchOptions struct {
+	// Number of parallel generations, values < 1 mean DefaultBatchConcurrency.
+	Concurrency int
+}
+
+// BatchResult is outcome of single batch item.
+type BatchResult struct {
+	// Index of item in params slice.
+	Index int
+	// Params of the item.
+	Params Params
+	// Generated image, nil if Err is set.
+	Image *Image
+	// Error of the item, e.g. ErrCensored.
+	Err error
+}
+
+// BatchError lists failed items of batch.
+type BatchError struct {
+	// Number of items in batch.
+	Total int
+	// Failed items ordered by index.
+	Failed []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%s: %d of %d, first %d: %v", ErrBatchFailed, len(e.Failed), e.Total, e.Failed[0].Index, e.Failed[0].Err)
+}
+
+// Is makes errors.Is(err, ErrBatchFailed) true
+func (e *BatchError) Is(target error) bool {
+	return target == ErrBatchFailed
+}
+
+// GenerateBatch generates image for every params with bounded concurrency.
+// Results are sent to returned channel as soon as items are done, in any order,
+// channel is closed after the last one. Failed item does not abort the rest,
+// when ctx is done remaining items get ctx error.
+func (k *Kand) GenerateBatch(ctx context.Context, ps []Params, o BatchOptions) <-chan BatchResult {
+	n := o.Concurrency
+	if n < 1 {
+		n = DefaultBatchConcurrency
+	}
+	if n > len(ps) {
+		n = len(ps)
+	}
+
+	// buffered for every item, so workers never block on slow reader
+	results := make(chan BatchResult, len(ps))
+	items := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				image, err := k.GetImageContext(ctx, ps[i])
+				results <- BatchResult{Index: i, Params: ps[i], Image: image, Err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for i := range ps {
+			items <- i
+		}
+		close(items)
+
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// CollectBatch reads all results from GenerateBatch channel and returns them ordered by index.
+// *BatchError is returned if some items failed, successful results are returned anyway.
+func CollectBatch(results <-chan BatchResult) ([]BatchResult, error) {
+	all := []BatchResult{}
+	for r := range results {
+		all = append(all, r)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Index < all[j].Index
+	})
+
+	e := &BatchError{Total: len(all)}
+	for _, r := range all {
+		if r.Err != nil {
+			e.Failed = append(e.Failed, r)
+		}
+	}
+
+	if len(e.Failed) > 0 {
+		return all, e
+	}
+
+	return all, nil
+}
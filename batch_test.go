@@ -0,0 +1,93 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// batchUUID makes query UUID of submitted task
+func batchUUID(p Params) string {
+	return p.GenerateParams.Query
+}
+
+// batchStatus reports task done, censored if its UUID is "censored"
+func batchStatus(id string, n int32) string {
+	censored := strconv.FormatBool(id == "censored")
+	return `{"uuid":"` + id + `","status":"DONE","images":["aGVsbG8="],"censored":` + censored + `}`
+}
+
+// TestGenerateBatch common test
+func TestGenerateBatch(t *testing.T) {
+	f := &fakeAPI{uuid: batchUUID, status: batchStatus, delay: 5 * time.Millisecond}
+	ts := f.start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	ps := []Params{}
+	for i := 0; i < 20; i++ {
+		ps = append(ps, NewParams("cat-"+strconv.Itoa(i)))
+	}
+	ps[5] = NewParams("censored")
+	ps[12] = NewParams("")
+
+	results, err := CollectBatch(k.GenerateBatch(context.Background(), ps, BatchOptions{Concurrency: 3}))
+
+	var e *BatchError
+	if !errors.As(err, &e) || !errors.Is(err, ErrBatchFailed) {
+		t.Fatalf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrBatchFailed, err)
+	}
+
+	if len(results) != len(ps) || e.Total != len(ps) {
+		t.Fatalf("\nwant results:\n\t\t%d \n\tgot:\n\t\t%d %d\n", len(ps), len(results), e.Total)
+	}
+
+	if len(e.Failed) != 2 || !errors.Is(e.Failed[0].Err, ErrCensored) || !errors.Is(e.Failed[1].Err, ErrEmptyPrompt) {
+		t.Errorf("\nwant censored and empty prompt failures, got:\n\t\t%+v\n", e.Failed)
+	}
+
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("\nwant index:\n\t\t%d \n\tgot:\n\t\t%d\n", i, r.Index)
+		}
+		if r.Err == nil && r.Image.UUID != ps[i].GenerateParams.Query {
+			t.Errorf("\nwant uuid:\n\t\t\"%s\" \n\tgot:\n\t\t\"%s\"\n", ps[i].GenerateParams.Query, r.Image.UUID)
+		}
+	}
+
+	if got := atomic.LoadInt32(&f.maxInFlight); got > 3 {
+		t.Errorf("\nwant max in flight:\n\t\t3 \n\tgot:\n\t\t%d\n", got)
+	}
+}
+
+// TestGenerateBatchCanceled checks that every item gets result when ctx is done
+func TestGenerateBatchCanceled(t *testing.T) {
+	ts := (&fakeAPI{uuid: batchUUID, status: batchStatus, delay: 5 * time.Millisecond}).start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ps := []Params{NewParams("a"), NewParams("b"), NewParams("c")}
+	n := 0
+	for r := range k.GenerateBatch(ctx, ps, BatchOptions{}) {
+		n++
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", context.Canceled, r.Err)
+		}
+	}
+
+	if n != len(ps) {
+		t.Errorf("\nwant results:\n\t\t%d \n\tgot:\n\t\t%d\n", len(ps), n)
+	}
+}
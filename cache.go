@@ -0,0 +1,391 @@
+package kandinsky
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCacheLocked is returned when cache directory lock is not released in time.
+var ErrCacheLocked = errors.New("kandinsky cache directory is locked")
+
+// ResultCache keeps generated images by CacheKey.
+// Implementations must be safe for concurrent use.
+type ResultCache interface {
+	// Get returns image by key, false if it is absent or expired.
+	Get(ctx context.Context, key string) (*Image, bool, error)
+	// Put saves image by key.
+	Put(ctx context.Context, key string, i *Image) error
+}
+
+// WithResultCache makes GetImage return cached image for the same params and model
+// instead of paying for new generation. Cache errors do not fail generation.
+func WithResultCache(c ResultCache) Option {
+	return func(k *Kand) {
+		k.cache = c
+	}
+}
+
+// CacheKey returns canonical hash of params with defaults applied and model ID.
+func CacheKey(p Params, modelID int) string {
+	setDefaultParams(&p)
+
+	// struct fields are marshaled in fixed order
+	b, _ := json.Marshal(&p)
+
+	h := sha256.New()
+	h.Write(b)
+	h.Write([]byte("\x00" + strconv.Itoa(modelID)))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedImage returns image from cache, cache errors are treated as miss
+func (k *Kand) cachedImage(ctx context.Context, key string) (*Image, bool) {
+	if k.cache == nil {
+		return nil, false
+	}
+
+	i, ok, err := k.cache.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	return i, true
+}
+
+// cacheImage saves image to cache, cache errors are ignored
+func (k *Kand) cacheImage(ctx context.Context, key string, i *Image) {
+	if k.cache == nil {
+		return
+	}
+
+	_ = k.cache.Put(ctx, key, i)
+}
+
+// copyImage returns copy of i not sharing Images with it
+func copyImage(i *Image) *Image {
+	c := *i
+	c.Images = append([]string(nil), i.Images...)
+
+	return &c
+}
+
+// imageSize returns size of base64 images in bytes
+func imageSize(i *Image) int64 {
+	var n int64
+	for _, s := range i.Images {
+		n += int64(len(s))
+	}
+
+	return n
+}
+
+// MemoryCacheOptions configures MemoryCache.
+type MemoryCacheOptions struct {
+	// How long image is kept, 0 means no expiration.
+	TTL time.Duration
+	// Maximum number of images, 0 means unlimited.
+	MaxEntries int
+	// Maximum size of base64 images in bytes, 0 means unlimited.
+	MaxBytes int64
+	// Clock for expiration, nil means real clock.
+	Clock Clock
+}
+
+// MemoryCache keeps images in memory and evicts least recently used ones.
+type MemoryCache struct {
+	o MemoryCacheOptions
+
+	mu sync.Mutex
+	// Entries from most to least recently used.
+	lru *list.List
+	// Entries by key.
+	entries map[string]*list.Element
+	// Size of all images.
+	size int64
+}
+
+// memoryEntry is image cached in memory
+type memoryEntry struct {
+	key     string
+	image   Image
+	size    int64
+	expires time.Time
+}
+
+// NewMemoryCache creates empty MemoryCache.
+func NewMemoryCache(o MemoryCacheOptions) *MemoryCache {
+	if o.Clock == nil {
+		o.Clock = realClock{}
+	}
+
+	return &MemoryCache{o: o, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns image by key, false if it is absent or expired.
+func (c *MemoryCache) Get(ctx context.Context, key string) (*Image, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*memoryEntry)
+	if !e.expires.IsZero() && !c.o.Clock.Now().Before(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+
+	c.lru.MoveToFront(el)
+
+	return copyImage(&e.image), true, nil
+}
+
+// Put saves image by key and evicts least recently used images over limits.
+func (c *MemoryCache) Put(ctx context.Context, key string, i *Image) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	e := &memoryEntry{key: key, image: *copyImage(i), size: imageSize(i)}
+	if c.o.TTL > 0 {
+		e.expires = c.o.Clock.Now().Add(c.o.TTL)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += e.size
+
+	for c.lru.Len() > 1 && ((c.o.MaxEntries > 0 && c.lru.Len() > c.o.MaxEntries) || (c.o.MaxBytes > 0 && c.size > c.o.MaxBytes)) {
+		c.remove(c.lru.Back())
+	}
+
+	return nil
+}
+
+// Len returns number of cached images.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// remove deletes entry
+func (c *MemoryCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*memoryEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size
+}
+
+// DiskCacheOptions configures DiskCache.
+type DiskCacheOptions struct {
+	// How long image is kept, 0 means no expiration.
+	TTL time.Duration
+	// Maximum size of cache files in bytes, 0 means unlimited.
+	MaxBytes int64
+	// How long to wait for directory lock, 0 means 10 seconds.
+	LockTimeout time.Duration
+}
+
+// DiskCache keeps images as json files in directory and evicts oldest ones.
+// Several processes may share the directory: files are written atomically
+// and changes are done under lock file, flock where it is available.
+type DiskCache struct {
+	dir string
+	o   DiskCacheOptions
+}
+
+// NewDiskCache creates DiskCache in dir, dir is created if not exists.
+func NewDiskCache(dir string, o DiskCacheOptions) (*DiskCache, error) {
+	if o.LockTimeout <= 0 {
+		o.LockTimeout = 10 * time.Second
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiskCache{dir: dir, o: o}, nil
+}
+
+// Get returns image by key, false if it is absent or expired.
+func (c *DiskCache) Get(ctx context.Context, key string) (*Image, bool, error) {
+	path := c.path(key)
+
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if c.expired(fi, time.Now()) {
+		return nil, false, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// evicted by another process
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	i := new(Image)
+	err = json.Unmarshal(b, i)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return i, true, nil
+}
+
+// Put saves image by key and evicts expired and oldest images over size limit.
+func (c *DiskCache) Put(ctx context.Context, key string, i *Image) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := c.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = writeFileAtomic(c.path(key), b)
+	if err != nil {
+		return err
+	}
+
+	return c.evict(key)
+}
+
+// path returns file path for key
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// expired reports whether cache file is expired at now
+func (c *DiskCache) expired(fi os.FileInfo, now time.Time) bool {
+	return c.o.TTL > 0 && now.Sub(fi.ModTime()) >= c.o.TTL
+}
+
+// evict removes expired files and oldest files over size limit, keep is never removed
+func (c *DiskCache) evict(keep string) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	files := []os.FileInfo{}
+	var size int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		if c.expired(fi, now) && e.Name() != keep+".json" {
+			os.Remove(filepath.Join(c.dir, e.Name()))
+			continue
+		}
+
+		files = append(files, fi)
+		size += fi.Size()
+	}
+
+	if c.o.MaxBytes <= 0 {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	for _, fi := range files {
+		if size <= c.o.MaxBytes {
+			break
+		}
+		if fi.Name() == keep+".json" {
+			continue
+		}
+		if os.Remove(filepath.Join(c.dir, fi.Name())) == nil {
+			size -= fi.Size()
+		}
+	}
+
+	return nil
+}
+
+// errLockHeld is returned by lockFile when lock is held by other owner
+var errLockHeld = errors.New("kandinsky cache lock is held")
+
+// lock takes lock file in directory, waits while other process holds it
+func (c *DiskCache) lock(ctx context.Context) (func(), error) {
+	path := filepath.Join(c.dir, ".lock")
+	deadline := time.Now().Add(c.o.LockTimeout)
+
+	for {
+		unlock, err := lockFile(path)
+		if err == nil {
+			return unlock, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrCacheLocked
+		}
+
+		err = sleep(ctx, realClock{}, 10*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeFileAtomic writes b to temp file and renames it to path
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
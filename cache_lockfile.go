@@ -0,0 +1,63 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package kandinsky
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// staleLock is age after which lock file of crashed process is broken
+const staleLock = time.Minute
+
+// lockFile creates lock file at path holding unique owner token, used where flock is not available.
+// Lock older than staleLock is left by crashed process and is broken.
+func lockFile(path string) (func(), error) {
+	token := lockToken()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err == nil {
+		_, err = f.WriteString(token)
+		f.Close()
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+
+		return func() { removeLock(path, token) }, nil
+	}
+	if !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+
+	// lock of crashed process
+	if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > staleLock {
+		if owner, err := os.ReadFile(path); err == nil {
+			removeLock(path, string(owner))
+		}
+	}
+
+	return nil, errLockHeld
+}
+
+// removeLock removes lock file at path only if it is still owned by token. Lock is renamed
+// to unique name before check, so of processes removing it at once only one succeeds,
+// and lock of other owner is put back unless path is taken again meanwhile.
+func removeLock(path, token string) {
+	moved := path + "." + lockToken() + ".stale"
+	if os.Rename(path, moved) != nil {
+		return
+	}
+
+	if owner, err := os.ReadFile(moved); err == nil && string(owner) != token {
+		os.Link(moved, path)
+	}
+	os.Remove(moved)
+}
+
+// lockToken returns token unique for process and call
+func lockToken() string {
+	return strconv.Itoa(os.Getpid()) + "." + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
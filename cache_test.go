@@ -0,0 +1,292 @@
+package kandinsky
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCacheKey common test
+func TestCacheKey(t *testing.T) {
+	p := NewParams("black cat")
+
+	withDefaults := p
+	withDefaults.Width, withDefaults.Height = 128, 128
+
+	testCases := []struct {
+		desc  string
+		p     Params
+		model int
+		same  bool
+	}{
+		{desc: "Same params", p: p, model: 4, same: true},
+		{desc: "Defaults applied", p: withDefaults, model: 4, same: true},
+		{desc: "Other model", p: p, model: 5, same: false},
+		{desc: "Other style", p: NewParams("black cat", ParamStyle(UHD)), model: 4, same: false},
+	}
+
+	key := CacheKey(p, 4)
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := CacheKey(tC.p, tC.model) == key; got != tC.same {
+				t.Errorf("\n%s:\n\twant same key:\n\t\t%t \n\tgot:\n\t\t%t\n", tC.desc, tC.same, got)
+			}
+		})
+	}
+}
+
+// TestMemoryCache common test
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	image := func(s string) *Image { return &Image{UUID: s, Images: []string{s}} }
+
+	testCases := []struct {
+		desc    string
+		o       MemoryCacheOptions
+		advance time.Duration
+		present []string
+		absent  []string
+	}{
+		{
+			desc:    "Least recently used evicted by entries",
+			o:       MemoryCacheOptions{MaxEntries: 2},
+			present: []string{"a", "c"},
+			absent:  []string{"b"},
+		},
+		{
+			desc:    "Least recently used evicted by size",
+			o:       MemoryCacheOptions{MaxBytes: 2},
+			present: []string{"a", "c"},
+			absent:  []string{"b"},
+		},
+		{
+			desc:    "Expired by TTL",
+			o:       MemoryCacheOptions{TTL: time.Minute},
+			advance: time.Minute,
+			absent:  []string{"a", "b", "c"},
+		},
+		{
+			desc:    "Not expired yet",
+			o:       MemoryCacheOptions{TTL: time.Minute},
+			advance: time.Second,
+			present: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(0, 0)}
+			tC.o.Clock = clock
+			c := NewMemoryCache(tC.o)
+
+			c.Put(ctx, "a", image("a"))
+			c.Put(ctx, "b", image("b"))
+			// a becomes recently used
+			c.Get(ctx, "a")
+			c.Put(ctx, "c", image("c"))
+
+			clock.After(tC.advance)
+
+			for _, key := range tC.present {
+				i, ok, err := c.Get(ctx, key)
+				if err != nil || !ok || i.UUID != key {
+					t.Errorf("\n%s: want %s cached, got:\n\t\t%v %t %v\n", tC.desc, key, i, ok, err)
+				}
+			}
+
+			for _, key := range tC.absent {
+				if _, ok, _ := c.Get(ctx, key); ok {
+					t.Errorf("\n%s: want %s evicted\n", tC.desc, key)
+				}
+			}
+		})
+	}
+}
+
+// TestDiskCache common test
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// two processes sharing directory
+	c1, err := NewDiskCache(dir, DiskCacheOptions{TTL: time.Hour, MaxBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("create cache error > %s", err)
+	}
+	c2, err := NewDiskCache(dir, DiskCacheOptions{TTL: time.Hour, MaxBytes: 1 << 20})
+	if err != nil {
+		t.Fatalf("create cache error > %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := c1
+			if i%2 == 1 {
+				c = c2
+			}
+			key := "key-" + strconv.Itoa(i)
+			if err := c.Put(ctx, key, &Image{UUID: key, Images: []string{"aGVsbG8="}}); err != nil {
+				t.Errorf("put error > %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	i, ok, err := c2.Get(ctx, "key-0")
+	if err != nil || !ok || i.UUID != "key-0" {
+		t.Fatalf("\nwant key-0 cached, got:\n\t\t%v %t %v\n", i, ok, err)
+	}
+
+	// expired file
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "key-1.json"), old, old)
+	if _, ok, _ := c1.Get(ctx, "key-1"); ok {
+		t.Errorf("\nwant key-1 expired\n")
+	}
+
+	// size limit keeps newest files
+	small, err := NewDiskCache(dir, DiskCacheOptions{MaxBytes: 1})
+	if err != nil {
+		t.Fatalf("create cache error > %s", err)
+	}
+	if err := small.Put(ctx, "newest", &Image{UUID: "newest"}); err != nil {
+		t.Fatalf("put error > %s", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	names := []string{}
+	for _, e := range entries {
+		// lock file is kept
+		if e.Name() != ".lock" {
+			names = append(names, e.Name())
+		}
+	}
+	if strings.Join(names, ",") != "newest.json" {
+		t.Errorf("\nwant files:\n\t\tnewest.json \n\tgot:\n\t\t%v\n", names)
+	}
+}
+
+// TestMemoryCacheCopies checks that images put and got do not share Images with cache
+func TestMemoryCacheCopies(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheOptions{})
+
+	put := &Image{UUID: "test-uuid", Images: []string{"aGVsbG8="}}
+	c.Put(ctx, "key", put)
+	put.AddBase64("cHV0")
+
+	got, _, _ := c.Get(ctx, "key")
+	got.AddBase64("d29ybGQ=")
+
+	again, ok, _ := c.Get(ctx, "key")
+	if !ok || again.Images[0] != "aGVsbG8=" {
+		t.Errorf("\nwant cached image:\n\t\t\"aGVsbG8=\" \n\tgot:\n\t\t%v\n", again)
+	}
+}
+
+// TestDiskCacheLocked checks lock timeout
+func TestDiskCacheLocked(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, DiskCacheOptions{LockTimeout: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("create cache error > %s", err)
+	}
+
+	unlock, err := c.lock(context.Background())
+	if err != nil {
+		t.Fatalf("lock error > %s", err)
+	}
+	defer unlock()
+
+	err = c.Put(context.Background(), "key", &Image{})
+	if err != ErrCacheLocked {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrCacheLocked, err)
+	}
+}
+
+// TestGetImageCached checks that cached image is not generated again
+func TestGetImageCached(t *testing.T) {
+	var runs int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case modelsPath:
+			w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+		case runPath:
+			atomic.AddInt32(&runs, 1)
+			w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+		default:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+		}
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithResultCache(NewMemoryCache(MemoryCacheOptions{})))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	for _, p := range []Params{params, params, NewParams("other")} {
+		i, err := k.GetImage(p)
+		if err != nil || i.UUID != "test-uuid" {
+			t.Fatalf("\nwant image, got:\n\t\t%v %v\n", i, err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&runs); got != 2 {
+		t.Errorf("\nwant run requests:\n\t\t2 \n\tgot:\n\t\t%d\n", got)
+	}
+}
+
+// TestDiskCacheLock checks that lock is exclusive and lock file of crashed process does not block
+func TestDiskCacheLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".lock")
+	c, err := NewDiskCache(dir, DiskCacheOptions{})
+	if err != nil {
+		t.Fatalf("create cache error > %s", err)
+	}
+
+	// lock file left by crashed process
+	os.WriteFile(path, []byte("crashed"), 0o644)
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(path, old, old)
+
+	var active, maxActive int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := c.lock(context.Background())
+			if err != nil {
+				t.Errorf("lock error > %s", err)
+				return
+			}
+			storeMax(&maxActive, atomic.AddInt32(&active, 1))
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("\nwant lock holders at once:\n\t\t1 \n\tgot:\n\t\t%d\n", maxActive)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.stale"))
+	if len(files) != 0 {
+		t.Errorf("\nwant no renamed locks left, got:\n\t\t%v\n", files)
+	}
+}
@@ -0,0 +1,357 @@
+package kandinsky
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by NewFromEnv and NewFromConfig, see also EnvKey and EnvSecret.
+const (
+	// Kandinsky API host, endpoint paths are appended to it
+	EnvBaseURL = "KAND_API_URL"
+	// Models endpoint
+	EnvAuthURL = "KAND_API_AUTH_URL"
+	// Run endpoint
+	EnvGenURL = "KAND_API_GEN_URL"
+	// Status endpoint, task UUID is appended to it
+	EnvCheckURL = "KAND_API_CHECK_URL"
+	// Availability endpoint
+	EnvAvailabilityURL = "KAND_API_AVAILABILITY_URL"
+	// Timeout for every single request, e.g. "30s"
+	EnvTimeout = "KAND_API_TIMEOUT"
+	// User-Agent header value
+	EnvUserAgent = "KAND_API_USER_AGENT"
+
+	EnvRetryMaxAttempts    = "KAND_API_RETRY_MAX_ATTEMPTS"
+	EnvRetryInitialBackoff = "KAND_API_RETRY_INITIAL_BACKOFF"
+	EnvRetryMaxBackoff     = "KAND_API_RETRY_MAX_BACKOFF"
+	EnvRetryMultiplier     = "KAND_API_RETRY_MULTIPLIER"
+	EnvRetryJitter         = "KAND_API_RETRY_JITTER"
+
+	EnvPollInitialDelay = "KAND_API_POLL_INITIAL_DELAY"
+	EnvPollInterval     = "KAND_API_POLL_INTERVAL"
+	EnvPollMultiplier   = "KAND_API_POLL_MULTIPLIER"
+	EnvPollMaxInterval  = "KAND_API_POLL_MAX_INTERVAL"
+	EnvPollMaxAttempts  = "KAND_API_POLL_MAX_ATTEMPTS"
+	EnvPollTimeout      = "KAND_API_POLL_TIMEOUT"
+)
+
+// ConfigError is returned when setting of config file or environment is missing or invalid.
+// errors.Is(err, ErrEmptyURL) is true for missing endpoint.
+type ConfigError struct {
+	// Config file key or environment variable, e.g. "check_url" or "KAND_API_TIMEOUT".
+	Key string
+	// Underlying error.
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("kandinsky config %s: %s", e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Duration is time.Duration read from string like "1m30s".
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// Config holds client settings read by NewFromConfig and NewFromEnv.
+//
+//	{
+//		"base_url": "https://api-key.fusionbrain.ai",
+//		"key": "your_key",
+//		"secret": "your_secret",
+//		"timeout": "30s",
+//		"retry": {"max_attempts": 5, "initial_backoff": "1s"},
+//		"poll": {"interval": "2s", "timeout": "5m"}
+//	}
+type Config struct {
+	// Kandinsky API host, endpoints not set explicitly are built from it.
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Models endpoint.
+	AuthURL string `json:"auth_url" yaml:"auth_url"`
+	// Run endpoint.
+	GenURL string `json:"gen_url" yaml:"gen_url"`
+	// Status endpoint, task UUID is appended to it.
+	CheckURL string `json:"check_url" yaml:"check_url"`
+	// Availability endpoint.
+	AvailabilityURL string `json:"availability_url" yaml:"availability_url"`
+	// The API key.
+	Key string `json:"key" yaml:"key"`
+	// The API secret.
+	Secret string `json:"secret" yaml:"secret"`
+	// Timeout for every single request, 0 means no timeout.
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+	// User-Agent header value, empty means Go default.
+	UserAgent string `json:"user_agent" yaml:"user_agent"`
+	// Retry policy, see RetryPolicy.
+	Retry RetryConfig `json:"retry" yaml:"retry"`
+	// Polling policy, see PollPolicy.
+	Poll PollConfig `json:"poll" yaml:"poll"`
+}
+
+// RetryConfig is RetryPolicy read from config file.
+type RetryConfig struct {
+	MaxAttempts    int      `json:"max_attempts" yaml:"max_attempts"`
+	InitialBackoff Duration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff" yaml:"max_backoff"`
+	Multiplier     float64  `json:"multiplier" yaml:"multiplier"`
+	Jitter         float64  `json:"jitter" yaml:"jitter"`
+}
+
+// PollConfig is PollPolicy read from config file.
+type PollConfig struct {
+	InitialDelay Duration `json:"initial_delay" yaml:"initial_delay"`
+	Interval     Duration `json:"interval" yaml:"interval"`
+	Multiplier   float64  `json:"multiplier" yaml:"multiplier"`
+	MaxInterval  Duration `json:"max_interval" yaml:"max_interval"`
+	MaxAttempts  int      `json:"max_attempts" yaml:"max_attempts"`
+	Timeout      Duration `json:"timeout" yaml:"timeout"`
+}
+
+// DefaultConfig returns config with default host, retry and polling policies.
+func DefaultConfig() Config {
+	r := DefaultRetryPolicy()
+	p := DefaultPollPolicy()
+
+	return Config{
+		Retry: RetryConfig{
+			MaxAttempts:    r.MaxAttempts,
+			InitialBackoff: Duration(r.InitialBackoff),
+			MaxBackoff:     Duration(r.MaxBackoff),
+			Multiplier:     r.Multiplier,
+			Jitter:         r.Jitter,
+		},
+		Poll: PollConfig{
+			InitialDelay: Duration(p.InitialDelay),
+			Interval:     Duration(p.Interval),
+			Multiplier:   p.Multiplier,
+			MaxInterval:  Duration(p.MaxInterval),
+			MaxAttempts:  p.MaxAttempts,
+			Timeout:      Duration(p.Timeout),
+		},
+	}
+}
+
+// NewFromEnv creates client configured by environment variables, see EnvBaseURL and others.
+// Options override environment, unset variables mean defaults.
+func NewFromEnv(opts ...Option) (*Kand, error) {
+	c := DefaultConfig()
+
+	err := c.LoadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.New(opts...)
+}
+
+// NewFromConfig creates client configured by JSON or YAML file at path, format is chosen by extension.
+// Environment variables override file and options override both, unset settings mean defaults.
+func NewFromConfig(path string, opts ...Option) (*Kand, error) {
+	c, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.LoadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.New(opts...)
+}
+
+// LoadConfig reads config from JSON file or from YAML file with .yaml or .yml extension.
+// Settings missing in file keep values of DefaultConfig.
+func LoadConfig(path string) (Config, error) {
+	c := DefaultConfig()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &c)
+	default:
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	return c, nil
+}
+
+// LoadEnv overrides settings of c by environment variables which are set.
+func (c *Config) LoadEnv() error {
+	strs := []struct {
+		name string
+		dst  *string
+	}{
+		{EnvBaseURL, &c.BaseURL},
+		{EnvAuthURL, &c.AuthURL},
+		{EnvGenURL, &c.GenURL},
+		{EnvCheckURL, &c.CheckURL},
+		{EnvAvailabilityURL, &c.AvailabilityURL},
+		{EnvKey, &c.Key},
+		{EnvSecret, &c.Secret},
+		{EnvUserAgent, &c.UserAgent},
+	}
+	for _, s := range strs {
+		if v, ok := os.LookupEnv(s.name); ok {
+			*s.dst = v
+		}
+	}
+
+	durations := []struct {
+		name string
+		dst  *Duration
+	}{
+		{EnvTimeout, &c.Timeout},
+		{EnvRetryInitialBackoff, &c.Retry.InitialBackoff},
+		{EnvRetryMaxBackoff, &c.Retry.MaxBackoff},
+		{EnvPollInitialDelay, &c.Poll.InitialDelay},
+		{EnvPollInterval, &c.Poll.Interval},
+		{EnvPollMaxInterval, &c.Poll.MaxInterval},
+		{EnvPollTimeout, &c.Poll.Timeout},
+	}
+	for _, d := range durations {
+		if v, ok := os.LookupEnv(d.name); ok {
+			err := d.dst.UnmarshalText([]byte(v))
+			if err != nil {
+				return &ConfigError{Key: d.name, Err: err}
+			}
+		}
+	}
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{EnvRetryMaxAttempts, &c.Retry.MaxAttempts},
+		{EnvPollMaxAttempts, &c.Poll.MaxAttempts},
+	}
+	for _, i := range ints {
+		if v, ok := os.LookupEnv(i.name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return &ConfigError{Key: i.name, Err: err}
+			}
+			*i.dst = n
+		}
+	}
+
+	floats := []struct {
+		name string
+		dst  *float64
+	}{
+		{EnvRetryMultiplier, &c.Retry.Multiplier},
+		{EnvRetryJitter, &c.Retry.Jitter},
+		{EnvPollMultiplier, &c.Poll.Multiplier},
+	}
+	for _, f := range floats {
+		if v, ok := os.LookupEnv(f.name); ok {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return &ConfigError{Key: f.name, Err: err}
+			}
+			*f.dst = n
+		}
+	}
+
+	return nil
+}
+
+// New creates client configured by c, opts are applied after config.
+func (c Config) New(opts ...Option) (*Kand, error) {
+	o, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(c.Key, c.Secret, append(o, opts...)...)
+}
+
+// Options returns options equal to settings of c except credentials.
+// Endpoints not set are built from BaseURL, from DefaultBaseURL if no endpoint is set at all.
+// *ConfigError matching ErrEmptyURL is returned when some endpoints are set and others can not be built.
+func (c Config) Options() ([]Option, error) {
+	base := c.BaseURL
+	if base == "" && c.AuthURL == "" && c.GenURL == "" && c.CheckURL == "" && c.AvailabilityURL == "" {
+		base = DefaultBaseURL
+	}
+	base = strings.TrimRight(base, "/")
+
+	urls := []struct {
+		key  string
+		url  *string
+		path string
+	}{
+		{"auth_url", &c.AuthURL, modelsPath},
+		{"gen_url", &c.GenURL, runPath},
+		{"check_url", &c.CheckURL, statusPath},
+		{"availability_url", &c.AvailabilityURL, availPath},
+	}
+	for _, u := range urls {
+		if *u.url != "" {
+			continue
+		}
+		if base == "" {
+			return nil, &ConfigError{Key: u.key, Err: ErrEmptyURL}
+		}
+		*u.url = base + u.path
+	}
+
+	return []Option{
+		func(k *Kand) {
+			k.authURL, k.genURL, k.checkURL, k.availURL = c.AuthURL, c.GenURL, c.CheckURL, c.AvailabilityURL
+		},
+		WithTimeout(time.Duration(c.Timeout)),
+		WithUserAgent(c.UserAgent),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts:    c.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(c.Retry.InitialBackoff),
+			MaxBackoff:     time.Duration(c.Retry.MaxBackoff),
+			Multiplier:     c.Retry.Multiplier,
+			Jitter:         c.Retry.Jitter,
+		}),
+		WithPollPolicy(PollPolicy{
+			InitialDelay: time.Duration(c.Poll.InitialDelay),
+			Interval:     time.Duration(c.Poll.Interval),
+			Multiplier:   c.Poll.Multiplier,
+			MaxInterval:  time.Duration(c.Poll.MaxInterval),
+			MaxAttempts:  c.Poll.MaxAttempts,
+			Timeout:      time.Duration(c.Poll.Timeout),
+		}),
+	}, nil
+}
@@ -0,0 +1,161 @@
+package kandinsky
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets config environment variables for the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		EnvBaseURL, EnvAuthURL, EnvGenURL, EnvCheckURL, EnvAvailabilityURL, EnvKey, EnvSecret, EnvTimeout, EnvUserAgent,
+		EnvRetryMaxAttempts, EnvRetryInitialBackoff, EnvRetryMaxBackoff, EnvRetryMultiplier, EnvRetryJitter,
+		EnvPollInitialDelay, EnvPollInterval, EnvPollMultiplier, EnvPollMaxInterval, EnvPollMaxAttempts, EnvPollTimeout,
+	} {
+		// Setenv restores previous value after the test
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+// TestNewFromEnv common test
+func TestNewFromEnv(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		env   map[string]string
+		check string
+		want  error
+	}{
+		{
+			desc:  "Default host",
+			env:   map[string]string{EnvKey: "key", EnvSecret: "secret"},
+			check: DefaultBaseURL + statusPath,
+			want:  nil,
+		},
+		{
+			desc:  "Base URL and explicit endpoint",
+			env:   map[string]string{EnvKey: "key", EnvSecret: "secret", EnvBaseURL: "http://local/", EnvCheckURL: "http://other/status/"},
+			check: "http://other/status/",
+			want:  nil,
+		},
+		{
+			desc: "Missing endpoint",
+			env:  map[string]string{EnvKey: "key", EnvSecret: "secret", EnvAuthURL: "http://local/models", EnvGenURL: "http://local/run"},
+			want: ErrEmptyURL,
+		},
+		{
+			desc: "Missing secret",
+			env:  map[string]string{EnvKey: "key"},
+			want: ErrEmptySecret,
+		},
+		{
+			desc: "Invalid duration",
+			env:  map[string]string{EnvKey: "key", EnvSecret: "secret", EnvTimeout: "30"},
+			want: &ConfigError{Key: EnvTimeout},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			clearEnv(t)
+			for name, v := range tC.env {
+				t.Setenv(name, v)
+			}
+
+			k, err := NewFromEnv()
+			var cfgErr *ConfigError
+			if want, ok := tC.want.(*ConfigError); ok {
+				if !errors.As(err, &cfgErr) || cfgErr.Key != want.Key {
+					t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, want.Key, err)
+				}
+				return
+			}
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := k.checkURL; got != tC.check {
+				t.Errorf("\n%s:\n\twant check url:\n\t\t\"%s\" \n\tgot:\n\t\t\"%s\"\n", tC.desc, tC.check, got)
+			}
+		})
+	}
+}
+
+// TestNewFromConfig checks file formats and precedence of options over env over file
+func TestNewFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{
+			"base_url": "http://file",
+			"key": "file-key",
+			"secret": "file-secret",
+			"timeout": "10s",
+			"retry": {"max_attempts": 5},
+			"poll": {"interval": "2s", "timeout": "5m"}
+		}`,
+		"config.yaml": `
+base_url: http://file
+key: file-key
+secret: file-secret
+timeout: 10s
+retry:
+  max_attempts: 5
+poll:
+  interval: 2s
+  timeout: 5m
+`,
+	}
+
+	for name, body := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			clearEnv(t)
+			t.Setenv(EnvKey, "env-key")
+			t.Setenv(EnvPollTimeout, "1m")
+
+			kd, err := NewFromConfig(path, WithTimeout(time.Second))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+			k := kd
+
+			if k.key != "env-key" || k.secret != "file-secret" {
+				t.Errorf("\nwant credentials:\n\t\tenv-key file-secret \n\tgot:\n\t\t%s %s\n", k.key, k.secret)
+			}
+
+			if k.genURL != "http://file"+runPath {
+				t.Errorf("\nwant gen url:\n\t\t\"%s\" \n\tgot:\n\t\t\"%s\"\n", "http://file"+runPath, k.genURL)
+			}
+
+			if k.timeout != time.Second {
+				t.Errorf("\nwant timeout:\n\t\t%s \n\tgot:\n\t\t%s\n", time.Second, k.timeout)
+			}
+
+			// settings missing in file keep defaults
+			want := DefaultRetryPolicy()
+			want.MaxAttempts = 5
+			if k.retry != want {
+				t.Errorf("\nwant retry:\n\t\t%+v \n\tgot:\n\t\t%+v\n", want, k.retry)
+			}
+
+			if k.poll.Interval != 2*time.Second || k.poll.Timeout != time.Minute || k.poll.MaxInterval != DefaultPollPolicy().MaxInterval {
+				t.Errorf("\nwant poll interval 2s, timeout 1m, default max interval, got:\n\t\t%+v\n", k.poll)
+			}
+		})
+	}
+
+	if _, err := NewFromConfig(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", os.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,203 @@
+package kandinsky
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Environment variables read by EnvCredentials by default
+const (
+	EnvKey    = "KAND_API_KEY"
+	EnvSecret = "KAND_API_SECRET"
+)
+
+// Credentials to authenticate requests to Kandinsky API.
+type Credentials struct {
+	// The API key.
+	Key string `json:"key"`
+	// The API secret.
+	Secret string `json:"secret"`
+}
+
+// validate returns error for empty key or secret
+func (c Credentials) validate() error {
+	if c.Key == "" {
+		return ErrEmptyKey
+	}
+
+	if c.Secret == "" {
+		return ErrEmptySecret
+	}
+
+	return nil
+}
+
+// CredentialsProvider is consulted for credentials before every request.
+// Implementations must be safe for concurrent use.
+type CredentialsProvider interface {
+	// Credentials returns current key and secret.
+	Credentials(ctx context.Context) (Credentials, error)
+	// Refresh reloads credentials after Kandinsky API rejected them.
+	Refresh(ctx context.Context) error
+}
+
+// WithCredentials sets provider of key and secret, key and secret passed to New are ignored then.
+// On ErrUnauthorized credentials are refreshed once and request is repeated.
+func WithCredentials(p CredentialsProvider) Option {
+	return func(k *Kand) {
+		k.creds = p
+	}
+}
+
+// credentials returns credentials for request
+func (k *Kand) credentials(ctx context.Context) (Credentials, error) {
+	if k.creds == nil {
+		return Credentials{Key: k.key, Secret: k.secret}, nil
+	}
+
+	c, err := k.creds.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return c, c.validate()
+}
+
+// refreshCredentials reloads credentials, false if they can not be refreshed
+func (k *Kand) refreshCredentials(ctx context.Context) bool {
+	if k.creds == nil {
+		return false
+	}
+
+	return k.creds.Refresh(ctx) == nil
+}
+
+// staticCredentials never change
+type staticCredentials Credentials
+
+// StaticCredentials returns provider of fixed key and secret.
+func StaticCredentials(key, secret string) CredentialsProvider {
+	return staticCredentials{Key: key, Secret: secret}
+}
+
+func (c staticCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+func (c staticCredentials) Refresh(ctx context.Context) error {
+	return ErrUnauthorized
+}
+
+// envCredentials reads environment variables on every request
+type envCredentials struct {
+	key, secret string
+}
+
+// EnvCredentials returns provider reading key and secret from environment variables
+// on every request, empty names mean EnvKey and EnvSecret.
+func EnvCredentials(keyVar, secretVar string) CredentialsProvider {
+	if keyVar == "" {
+		keyVar = EnvKey
+	}
+	if secretVar == "" {
+		secretVar = EnvSecret
+	}
+
+	return envCredentials{key: keyVar, secret: secretVar}
+}
+
+func (c envCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials{Key: os.Getenv(c.key), Secret: os.Getenv(c.secret)}, nil
+}
+
+func (c envCredentials) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// FileCredentials reads key and secret from json file and reloads it when file is changed
+//
+//	{
+//		"key": "your_key",
+//		"secret": "your_secret"
+//	}
+type FileCredentials struct {
+	path string
+
+	mu    sync.Mutex
+	creds Credentials
+	// Modification time of loaded file.
+	mtime time.Time
+}
+
+// NewFileCredentials loads credentials from json file at path.
+func NewFileCredentials(path string) (*FileCredentials, error) {
+	c := &FileCredentials{path: path}
+
+	err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Credentials returns credentials from file, file is reloaded if it was changed.
+func (c *FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fi, err := os.Stat(c.path)
+	if err == nil && !fi.ModTime().Equal(c.mtime) {
+		err = c.loadLocked()
+		if err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	return c.creds, nil
+}
+
+// Refresh reloads file.
+func (c *FileCredentials) Refresh(ctx context.Context) error {
+	return c.load()
+}
+
+// load reads file
+func (c *FileCredentials) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.loadLocked()
+}
+
+// loadLocked reads file, c.mu must be held
+func (c *FileCredentials) loadLocked() error {
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	creds := Credentials{}
+	err = json.Unmarshal(b, &creds)
+	if err != nil {
+		return err
+	}
+
+	err = creds.validate()
+	if err != nil {
+		return err
+	}
+
+	c.creds = creds
+	c.mtime = fi.ModTime()
+
+	return nil
+}
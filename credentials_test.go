@@ -0,0 +1,160 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rotatingCredentials switches to next credentials on Refresh
+type rotatingCredentials struct {
+	mu        sync.Mutex
+	all       []Credentials
+	refreshes int
+}
+
+func (c *rotatingCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.all[0], nil
+}
+
+func (c *rotatingCredentials) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshes++
+	if len(c.all) > 1 {
+		c.all = c.all[1:]
+	}
+	return nil
+}
+
+// TestCredentialsRefresh common test
+func TestCredentialsRefresh(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		all       []Credentials
+		requests  int32
+		refreshes int
+		want      error
+	}{
+		{
+			desc:      "Rotated credentials",
+			all:       []Credentials{{Key: "old", Secret: "old"}, {Key: "new", Secret: "new"}},
+			requests:  2,
+			refreshes: 1,
+			want:      nil,
+		},
+		{
+			desc:      "Refreshed once only",
+			all:       []Credentials{{Key: "old", Secret: "old"}},
+			requests:  2,
+			refreshes: 1,
+			want:      ErrUnauthorized,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var n int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&n, 1)
+				if r.Header.Get("X-Key") != "Key new" || r.Header.Get("X-Secret") != "Secret new" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+			}))
+			defer ts.Close()
+
+			p := &rotatingCredentials{all: tC.all}
+			k, err := New("", "", WithBaseURL(ts.URL), WithCredentials(p))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+			k.Model = Model{ID: 4}
+
+			// run request is not idempotent, but rejected one is safe to repeat
+			_, err = k.GetImageUUID(params)
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if got := atomic.LoadInt32(&n); got != tC.requests || p.refreshes != tC.refreshes {
+				t.Errorf("\n%s:\n\twant requests and refreshes:\n\t\t%d %d \n\tgot:\n\t\t%d %d\n", tC.desc, tC.requests, tC.refreshes, got, p.refreshes)
+			}
+		})
+	}
+}
+
+// TestCredentialsProviders common test
+func TestCredentialsProviders(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "creds.json")
+	os.WriteFile(path, []byte(`{"key":"file-key","secret":"file-secret"}`), 0o600)
+
+	file, err := NewFileCredentials(path)
+	if err != nil {
+		t.Fatalf("load file credentials error > %s", err)
+	}
+
+	t.Setenv("TEST_KAND_KEY", "env-key")
+	t.Setenv("TEST_KAND_SECRET", "env-secret")
+
+	testCases := []struct {
+		desc   string
+		p      CredentialsProvider
+		change func()
+		want   Credentials
+	}{
+		{
+			desc: "Static",
+			p:    StaticCredentials("key", "secret"),
+			want: Credentials{Key: "key", Secret: "secret"},
+		},
+		{
+			desc:   "Environment read on every request",
+			p:      EnvCredentials("TEST_KAND_KEY", "TEST_KAND_SECRET"),
+			change: func() { t.Setenv("TEST_KAND_KEY", "rotated-key") },
+			want:   Credentials{Key: "rotated-key", Secret: "env-secret"},
+		},
+		{
+			desc: "File reloaded when changed",
+			p:    file,
+			change: func() {
+				os.WriteFile(path, []byte(`{"key":"rotated-key","secret":"rotated-secret"}`), 0o600)
+				future := time.Now().Add(time.Hour)
+				os.Chtimes(path, future, future)
+			},
+			want: Credentials{Key: "rotated-key", Secret: "rotated-secret"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if tC.change != nil {
+				tC.change()
+			}
+
+			c, err := tC.p.Credentials(ctx)
+			if err != nil || c != tC.want {
+				t.Errorf("\n%s:\n\twant:\n\t\t%+v \n\tgot:\n\t\t%+v %v\n", tC.desc, tC.want, c, err)
+			}
+		})
+	}
+
+	if _, err := New("", "", WithCredentials(EnvCredentials("TEST_KAND_NONE", ""))); err != nil {
+		t.Errorf("create Kandinsky instance with provider error > %s", err)
+	}
+
+	if _, err := NewFileCredentials(filepath.Join(t.TempDir(), "none.json")); err == nil {
+		t.Errorf("want error for missing credentials file")
+	}
+}
@@ -0,0 +1,73 @@
+package kandinsky
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// APIError is returned for any non-2xx response from Kandinsky API.
+// It matches sentinel errors with errors.Is, e.g. errors.Is(err, ErrUnauthorized).
+type APIError struct {
+	// HTTP status code of the response.
+	StatusCode int
+	// Error response decoded from body, empty if body is not json.
+	Response ErrResponse
+	// Raw response body, credentials echoed by server are masked.
+	Body []byte
+	// URL of the endpoint.
+	Endpoint string
+	// Request sent to the endpoint, credentials in headers are masked.
+	Request *http.Request
+	// Delay from Retry-After header, 0 if header is absent.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := e.Response.Error
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	s := fmt.Sprintf("error from Kandinsky API: status %d %s", e.StatusCode, msg)
+	if e.Response.Message != "" {
+		s += " > " + e.Response.Message
+	}
+	if e.Endpoint != "" {
+		s += " (" + e.Endpoint + ")"
+	}
+
+	return s
+}
+
+// Unwrap returns sentinel error for status code
+func (e *APIError) Unwrap() error {
+	return checkStatusCode(e.StatusCode)
+}
+
+// checkResponse returns *APIError if response status is not 2xx, body is consumed in that case
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+
+	e := &APIError{
+		StatusCode: res.StatusCode,
+		Request:    redactRequest(res.Request),
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		e.Endpoint = res.Request.URL.String()
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err == nil {
+		e.Body = []byte(Redact(string(b), requestSecrets(res.Request)...))
+		// body may be empty or not json, status code is enough then
+		_ = json.Unmarshal(e.Body, &e.Response)
+	}
+
+	return e
+}
@@ -0,0 +1,91 @@
+package kandinsky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAPIError common test
+func TestAPIError(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		code    int
+		body    string
+		want    error
+		message string
+	}{
+		{
+			desc:    "Bad request with json body",
+			code:    http.StatusBadRequest,
+			body:    `{"timestamp":"2024-03-04T13:46:55.473+00:00","status":400,"error":"Bad Request","message":"wrong params","path":"/key/api/v1/models"}`,
+			want:    ErrBadRequest,
+			message: "wrong params",
+		},
+		{
+			desc: "Unauthorized without body",
+			code: http.StatusUnauthorized,
+			want: ErrUnauthorized,
+		},
+		{
+			desc: "Forbidden",
+			code: http.StatusForbidden,
+			want: ErrForbidden,
+		},
+		{
+			desc: "Too many requests",
+			code: http.StatusTooManyRequests,
+			want: ErrTooManyRequests,
+		},
+		{
+			desc: "Service unavailable",
+			code: http.StatusServiceUnavailable,
+			body: "<html>down</html>",
+			want: ErrServiceUnavailable,
+		},
+		{
+			desc: "Unknown status",
+			code: http.StatusTeapot,
+			want: ErrStatusNot200,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tC.code)
+				w.Write([]byte(tC.body))
+			}))
+			defer ts.Close()
+
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithRetryPolicy(RetryPolicy{}))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			_, err = k.SetModel()
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("\n%s: error is not *APIError > %v", tC.desc, err)
+			}
+
+			if apiErr.StatusCode != tC.code {
+				t.Errorf("\n%s:\n\twant status:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.code, apiErr.StatusCode)
+			}
+			if apiErr.Response.Message != tC.message {
+				t.Errorf("\n%s:\n\twant message:\n\t\t%q \n\tgot:\n\t\t%q\n", tC.desc, tC.message, apiErr.Response.Message)
+			}
+			if apiErr.Endpoint != ts.URL+modelsPath {
+				t.Errorf("\n%s:\n\twant endpoint:\n\t\t%q \n\tgot:\n\t\t%q\n", tC.desc, ts.URL+modelsPath, apiErr.Endpoint)
+			}
+			if apiErr.Request == nil || apiErr.Request.Method != http.MethodGet {
+				t.Errorf("\n%s: request is not preserved", tC.desc)
+			}
+		})
+	}
+}
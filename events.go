@@ -0,0 +1,60 @@
+package kandinsky
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType is kind of polling event.
+type EventType int
+
+const (
+	// Status request is done
+	EventPoll EventType = iota
+	// Status of task is changed, e.g. from INITIAL to PROCESSING
+	EventStatusChange
+	// Polling is finished, Err is set if task failed or polling stopped
+	EventDone
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPoll:
+		return "poll"
+	case EventStatusChange:
+		return "status change"
+	case EventDone:
+		return "done"
+	}
+
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event describes progress of task while CheckImage polls it.
+type Event struct {
+	// Kind of event.
+	Type EventType
+	// UUID of the task.
+	UUID string
+	// Last status received from Kandinsky API.
+	Status string
+	// Status before change, set for EventStatusChange only.
+	PrevStatus string
+	// Number of status requests done.
+	Attempt int
+	// Time spent polling.
+	Elapsed time.Duration
+	// Final error, set for EventDone only.
+	Err error
+}
+
+// Observer receives polling events, it is called synchronously from polling goroutine
+// so it must be fast and safe for concurrent use.
+type Observer func(e Event)
+
+// WithObserver sets observer receiving polling events of every task, e.g. to show progress.
+func WithObserver(o Observer) Option {
+	return func(k *Kand) {
+		k.observer = o
+	}
+}
@@ -0,0 +1,89 @@
+package kandinsky
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestObserver common test
+func TestObserver(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		doneAfter int32
+		policy    PollPolicy
+		events    []string
+		attempts  int
+		want      error
+	}{
+		{
+			desc:      "Status transitions until done",
+			doneAfter: 3,
+			policy:    PollPolicy{Interval: time.Second},
+			events: []string{
+				"poll PROCESSING", "status change PROCESSING",
+				"poll PROCESSING",
+				"poll DONE", "status change DONE",
+				"done DONE",
+			},
+			attempts: 3,
+			want:     nil,
+		},
+		{
+			desc:      "Final outcome on timeout",
+			doneAfter: 0,
+			policy:    PollPolicy{Interval: time.Second, MaxAttempts: 2},
+			events: []string{
+				"poll PROCESSING", "status change PROCESSING",
+				"poll PROCESSING",
+				"done PROCESSING",
+			},
+			attempts: 2,
+			want:     ErrPollTimeout,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ts := (&fakeAPI{status: doneAfter(tC.doneAfter)}).start(t)
+
+			var mu sync.Mutex
+			events := []Event{}
+			observer := func(e Event) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, e)
+			}
+
+			c := &fakeClock{now: time.Unix(0, 0)}
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithClock(c), WithPollPolicy(tC.policy), WithObserver(observer))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			_, err = k.CheckImage(&UUID{ID: "test-uuid", Status: "INITIAL"})
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			got := []string{}
+			for _, e := range events {
+				got = append(got, e.Type.String()+" "+e.Status)
+			}
+			if !reflect.DeepEqual(got, tC.events) {
+				t.Errorf("\n%s:\n\twant events:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, tC.events, got)
+			}
+
+			last := events[len(events)-1]
+			if last.UUID != "test-uuid" || last.Attempt != tC.attempts || !errors.Is(last.Err, tC.want) {
+				t.Errorf("\n%s:\n\twant final event:\n\t\t%d %v \n\tgot:\n\t\t%+v\n", tC.desc, tC.attempts, tC.want, last)
+			}
+
+			if events[1].PrevStatus != "INITIAL" || events[1].Elapsed != 0 {
+				t.Errorf("\n%s:\n\twant first change from INITIAL, got:\n\t\t%+v\n", tC.desc, events[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package kandinsky
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAPI is Kandinsky API shared by tests, zero fakeAPI offers model 4,
+// submits task "test-uuid" and reports it done at the first status request
+type fakeAPI struct {
+	// UUID of submitted task with params p, nil means "test-uuid".
+	uuid func(p Params) string
+	// Body of response to n-th status request of task id, nil means done.
+	status func(id string, n int32) string
+	// Body of response to n-th availability request, nil means active.
+	availability func(n int32) string
+	// Sleep before responding to run and status requests.
+	delay time.Duration
+
+	// Number of run, status and availability requests.
+	runs, statuses, availabilities int32
+	// Most run and status requests served at once.
+	maxInFlight int32
+
+	inFlight int32
+	mu       sync.Mutex
+	polls    map[string]int32
+}
+
+// start serves f until test ends
+func (f *fakeAPI) start(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == modelsPath:
+			w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+		case r.URL.Path == availPath:
+			if r.URL.Query().Get("model_id") != "4" {
+				t.Errorf("model_id:\n\twant:\n\t\t\"4\" \n\tgot:\n\t\t\"%s\"\n", r.URL.Query().Get("model_id"))
+			}
+			n := atomic.AddInt32(&f.availabilities, 1)
+			if f.availability == nil {
+				w.Write([]byte(`{"status":"ACTIVE"}`))
+				return
+			}
+			w.Write([]byte(f.availability(n)))
+		case r.URL.Path == runPath:
+			atomic.AddInt32(&f.runs, 1)
+			defer f.enter()()
+
+			id := "test-uuid"
+			if f.uuid != nil {
+				r.ParseMultipartForm(1 << 20)
+				p := Params{}
+				err := json.Unmarshal([]byte(r.FormValue("params")), &p)
+				if err != nil {
+					t.Errorf("decode params error > %s", err)
+				}
+				id = f.uuid(p)
+			}
+			w.Write([]byte(`{"uuid":"` + id + `","status":"INITIAL"}`))
+		case strings.HasPrefix(r.URL.Path, statusPath):
+			atomic.AddInt32(&f.statuses, 1)
+			defer f.enter()()
+
+			id := strings.TrimPrefix(r.URL.Path, statusPath)
+			f.mu.Lock()
+			if f.polls == nil {
+				f.polls = map[string]int32{}
+			}
+			f.polls[id]++
+			n := f.polls[id]
+			f.mu.Unlock()
+
+			if f.status == nil {
+				w.Write([]byte(doneBody(id)))
+				return
+			}
+			w.Write([]byte(f.status(id, n)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// enter counts request in flight for delay, call returned func when it is served
+func (f *fakeAPI) enter() func() {
+	storeMax(&f.maxInFlight, atomic.AddInt32(&f.inFlight, 1))
+	time.Sleep(f.delay)
+
+	return func() {
+		atomic.AddInt32(&f.inFlight, -1)
+	}
+}
+
+// storeMax sets *max to n if n is greater
+func storeMax(max *int32, n int32) {
+	for {
+		m := atomic.LoadInt32(max)
+		if n <= m || atomic.CompareAndSwapInt32(max, m, n) {
+			return
+		}
+	}
+}
+
+// doneBody is status response of done task id
+func doneBody(id string) string {
+	return `{"uuid":"` + id + `","status":"DONE","images":["aGVsbG8="],"censored":false}`
+}
+
+// doneAfter reports task PROCESSING until n-th status request, 0 means forever
+func doneAfter(after int32) func(id string, n int32) string {
+	return func(id string, n int32) string {
+		if after > 0 && n >= after {
+			return doneBody(id)
+		}
+		return `{"uuid":"` + id + `","status":"PROCESSING"}`
+	}
+}
+
+// doneWhen reports task PROCESSING until release is closed
+func doneWhen(release <-chan struct{}) func(id string, n int32) string {
+	return func(id string, n int32) string {
+		select {
+		case <-release:
+			return doneBody(id)
+		default:
+			return `{"uuid":"` + id + `","status":"PROCESSING"}`
+		}
+	}
+}
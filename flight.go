@@ -0,0 +1,86 @@
+package kandinsky
+
+import (
+	"context"
+	"sync"
+)
+
+// WithDeduplication sets whether concurrent GetImage calls with identical params share
+// one task and one image, true by default.
+func WithDeduplication(on bool) Option {
+	return func(k *Kand) {
+		k.noDedup = !on
+	}
+}
+
+// flightCall is generation shared by concurrent callers
+type flightCall struct {
+	// Closed when generation is finished.
+	done chan struct{}
+	// Cancels generation when all callers left.
+	cancel context.CancelFunc
+	// Number of callers waiting, guarded by flightGroup.mu.
+	waiters int
+
+	image *Image
+	err   error
+}
+
+// flightGroup coalesces concurrent generations with the same key
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn once for all concurrent callers with key and returns its result to each of them.
+// fn runs with context canceled only when every caller's ctx is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*Image, error)) (*Image, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	c, ok := g.calls[key]
+	if ok {
+		c.waiters++
+	} else {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &flightCall{done: make(chan struct{}), cancel: cancel, waiters: 1}
+		g.calls[key] = c
+
+		go func() {
+			defer cancel()
+			c.image, c.err = fn(fctx)
+
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		// canceled call is forgotten at once, new caller starts own one
+		if c.waiters == 0 {
+			c.cancel()
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	case <-c.done:
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	// every caller gets own copy
+	return copyImage(c.image), nil
+}
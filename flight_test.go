@@ -0,0 +1,142 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDeduplication common test
+func TestDeduplication(t *testing.T) {
+	testCases := []struct {
+		desc string
+		opts []Option
+		runs int32
+	}{
+		{
+			desc: "Identical requests share task",
+			opts: nil,
+			runs: 1,
+		},
+		{
+			desc: "Deduplication disabled",
+			opts: []Option{WithDeduplication(false)},
+			runs: 10,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			release := make(chan struct{})
+			f := &fakeAPI{status: doneWhen(release)}
+			ts := f.start(t)
+
+			opts := append([]Option{WithBaseURL(ts.URL), WithPollPolicy(PollPolicy{Interval: time.Millisecond})}, tC.opts...)
+			k, err := New("key", "secret", opts...)
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			images := make(chan *Image, 10)
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					i, err := k.GetImage(params)
+					if err != nil {
+						t.Errorf("get image error > %s", err)
+						return
+					}
+					images <- i
+				}()
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(images)
+
+			if got := atomic.LoadInt32(&f.runs); got != tC.runs {
+				t.Errorf("\n%s:\n\twant run requests:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.runs, got)
+			}
+
+			seen := map[*Image]bool{}
+			for i := range images {
+				if seen[i] || i.UUID != "test-uuid" {
+					t.Errorf("\n%s: want own copy of image, got:\n\t\t%p %v\n", tC.desc, i, i)
+				}
+				seen[i] = true
+			}
+		})
+	}
+}
+
+// TestDeduplicationCanceled checks that canceled caller does not abort others
+func TestDeduplicationCanceled(t *testing.T) {
+	release := make(chan struct{})
+	f := &fakeAPI{status: doneWhen(release)}
+	ts := f.start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithPollPolicy(PollPolicy{Interval: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := k.GetImageContext(ctx, params)
+		first <- err
+	}()
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := k.GetImageContext(context.Background(), params)
+		second <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", context.Canceled, err)
+	}
+
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("second caller error > %s", err)
+	}
+
+	if got := atomic.LoadInt32(&f.runs); got != 1 {
+		t.Errorf("\nwant run requests:\n\t\t1 \n\tgot:\n\t\t%d\n", got)
+	}
+}
+
+// TestFlightJoinAfterCancel checks that caller coming after all others left starts new generation
+func TestFlightJoinAfterCancel(t *testing.T) {
+	var g flightGroup
+
+	// first generation ends long after its only caller left
+	hold := make(chan struct{})
+	defer close(hold)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := g.do(ctx, "key", func(ctx context.Context) (*Image, error) {
+		<-ctx.Done()
+		<-hold
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", context.Canceled, err)
+	}
+
+	i, err := g.do(context.Background(), "key", func(ctx context.Context) (*Image, error) {
+		return &Image{UUID: "test-uuid"}, nil
+	})
+	if err != nil || i.UUID != "test-uuid" {
+		t.Errorf("\nwant new generation result, got:\n\t\t%+v %v\n", i, err)
+	}
+}
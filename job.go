@@ -0,0 +1,171 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrJobNotDone is returned by Job.Result while task is in progress.
+var ErrJobNotDone = errors.New("kandinsky job is not done yet")
+
+// Job is handle of generation task polled in background.
+// It is safe for concurrent use.
+type Job struct {
+	// UUID of the task.
+	uuid string
+	// Stops background polling.
+	cancel context.CancelFunc
+	// Closed when polling is finished.
+	done chan struct{}
+
+	// Guards fields below.
+	mu sync.Mutex
+	// Last status received from Kandinsky API.
+	status string
+	// Generated image, nil until task is done.
+	image *Image
+	// Error of the task.
+	err error
+	// Called once when image is returned by Result.
+	deliver func()
+	// Guards deliver.
+	delivered sync.Once
+}
+
+// Submit sends generation task and returns at once, task is polled in background.
+// ctx is used only for submitting, use Job.Cancel to stop polling.
+func (k *Kand) Submit(ctx context.Context, p Params) (*Job, error) {
+	err := k.checkAvailability(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = k.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := k.GetImageUUIDContext(ctx, p)
+	err = k.keepUnsaved(ctx, u, err)
+	if err != nil {
+		k.limiter.release()
+		return nil, err
+	}
+
+	return k.startJob(u, k.limiter.release), nil
+}
+
+// ResumeJob reattaches to task started earlier, e.g. by another process, and polls it in background.
+// Resumed task does not count in RateLimit.MaxInFlight, its status polls do count in requests.
+func (k *Kand) ResumeJob(uuid string) (*Job, error) {
+	if uuid == "" {
+		return nil, ErrEmptyUUID
+	}
+
+	// submission time is known if task is in store
+	u := &UUID{ID: uuid}
+	if k.store != nil {
+		if r, err := k.store.Get(context.Background(), uuid); err == nil {
+			u.Status, u.submitted = r.Status, r.SubmittedAt
+		}
+	}
+
+	return k.startJob(u, nil), nil
+}
+
+// startJob starts polling task u in background, release is called when polling is finished
+func (k *Kand) startJob(u *UUID, release func()) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &Job{
+		uuid:   u.ID,
+		status: u.Status,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		// record is kept in store until image is collected
+		deliver: func() { k.deleteJob(u.ID) },
+	}
+
+	go func() {
+		defer cancel()
+		if release != nil {
+			defer release()
+		}
+
+		image, err := k.pollImage(ctx, u, j.observe)
+		k.finishJob(u.ID, image, err, false)
+
+		j.mu.Lock()
+		j.image, j.err = image, err
+		j.mu.Unlock()
+
+		close(j.done)
+	}()
+
+	return j
+}
+
+// observe saves last status of task
+func (j *Job) observe(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status = e.Status
+}
+
+// UUID returns UUID of the task, pass it to ResumeJob to reattach later.
+func (j *Job) UUID() string {
+	return j.uuid
+}
+
+// Status returns last status received from Kandinsky API, e.g. "PROCESSING".
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.status
+}
+
+// Done returns channel closed when task is finished, failed or polling is stopped.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Result returns image and error of finished task, ErrJobNotDone while task is in progress.
+// Once image is returned task record is deleted from JobStore.
+func (j *Job) Result() (*Image, error) {
+	select {
+	case <-j.done:
+	default:
+		return nil, ErrJobNotDone
+	}
+
+	j.mu.Lock()
+	image, err := j.image, j.err
+	j.mu.Unlock()
+
+	if err == nil {
+		j.delivered.Do(j.deliver)
+	}
+
+	return image, err
+}
+
+// Wait waits until task is finished and returns its result.
+// When ctx is done ctx error is returned and polling goes on.
+func (j *Job) Wait(ctx context.Context) (*Image, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-j.done:
+	}
+
+	return j.Result()
+}
+
+// Cancel stops polling, Result returns context.Canceled then.
+// Task in Kandinsky API is not canceled and may be resumed with ResumeJob.
+func (j *Job) Cancel() {
+	j.cancel()
+}
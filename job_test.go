@@ -0,0 +1,106 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSubmit common test
+func TestSubmit(t *testing.T) {
+	release := make(chan struct{})
+	ts := (&fakeAPI{status: doneWhen(release)}).start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithPollPolicy(PollPolicy{Interval: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	// submit context is done right after Submit, polling must go on
+	ctx, cancel := context.WithCancel(context.Background())
+	j, err := k.Submit(ctx, params)
+	cancel()
+	if err != nil {
+		t.Fatalf("submit error > %s", err)
+	}
+
+	if j.UUID() != "test-uuid" {
+		t.Errorf("\nwant uuid:\n\t\t\"test-uuid\" \n\tgot:\n\t\t\"%s\"\n", j.UUID())
+	}
+
+	if _, err := j.Result(); err != ErrJobNotDone {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrJobNotDone, err)
+	}
+
+	_, err = j.Wait(timeoutContext(t, 20*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", context.DeadlineExceeded, err)
+	}
+
+	if j.Status() != "PROCESSING" {
+		t.Errorf("\nwant status:\n\t\t\"PROCESSING\" \n\tgot:\n\t\t\"%s\"\n", j.Status())
+	}
+
+	close(release)
+
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("job is not done")
+	}
+
+	i, err := j.Result()
+	if err != nil || len(i.Images) != 1 {
+		t.Errorf("\nwant image, got:\n\t\t%v %v\n", i, err)
+	}
+
+	if j.Status() != "DONE" {
+		t.Errorf("\nwant status:\n\t\t\"DONE\" \n\tgot:\n\t\t\"%s\"\n", j.Status())
+	}
+}
+
+// TestResumeJob common test
+func TestResumeJob(t *testing.T) {
+	ts := (&fakeAPI{}).start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	if _, err := k.ResumeJob(""); err != ErrEmptyUUID {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrEmptyUUID, err)
+	}
+
+	j, err := k.ResumeJob("test-uuid")
+	if err != nil {
+		t.Fatalf("resume job error > %s", err)
+	}
+
+	i, err := j.Wait(timeoutContext(t, 5*time.Second))
+	if err != nil || i.UUID != "test-uuid" {
+		t.Errorf("\nwant image, got:\n\t\t%v %v\n", i, err)
+	}
+}
+
+// TestJobCancel checks that Cancel stops polling
+func TestJobCancel(t *testing.T) {
+	ts := (&fakeAPI{status: doneAfter(0)}).start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithPollPolicy(PollPolicy{Interval: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	j, err := k.ResumeJob("test-uuid")
+	if err != nil {
+		t.Fatalf("resume job error > %s", err)
+	}
+	j.Cancel()
+
+	_, err = j.Wait(timeoutContext(t, 5*time.Second))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", context.Canceled, err)
+	}
+}
@@ -0,0 +1,362 @@
+package kandinsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned by JobStore.Get for unknown UUID.
+var ErrJobNotFound = errors.New("kandinsky job is not found in store")
+
+// JobRecord is generation task saved in JobStore.
+type JobRecord struct {
+	// UUID of the task.
+	UUID string `json:"uuid"`
+	// Params the task was submitted with.
+	Params Params `json:"params"`
+	// Model the task was submitted to.
+	ModelID int `json:"model_id"`
+	// Last status received from Kandinsky API.
+	Status string `json:"status"`
+	// When the task was submitted.
+	SubmittedAt time.Time `json:"submitted_at"`
+	// When the record was updated.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobStore records submitted tasks so they can be resumed after restart.
+// Record is deleted when task failed or its image is returned to caller or saved to ResultCache.
+// Implementations must be safe for concurrent use.
+type JobStore interface {
+	// Put adds record or replaces record with the same UUID.
+	Put(ctx context.Context, r JobRecord) error
+	// Get returns record by UUID or ErrJobNotFound.
+	Get(ctx context.Context, uuid string) (JobRecord, error)
+	// Pending returns all records ordered by submit time.
+	Pending(ctx context.Context) ([]JobRecord, error)
+	// Delete removes record by UUID, unknown UUID is not an error.
+	Delete(ctx context.Context, uuid string) error
+}
+
+// JobStoreError is returned by GetImageUUID next to UUID when task was submitted but could not
+// be saved to JobStore, task UUID allows to poll it or resume it with ResumeJob.
+// GetImage and Submit log it and go on polling the task.
+type JobStoreError struct {
+	// UUID of the submitted task.
+	UUID string
+	// Error from JobStore.
+	Err error
+}
+
+func (e *JobStoreError) Error() string {
+	return fmt.Sprintf("kandinsky save job %s to store: %v", e.UUID, e.Err)
+}
+
+func (e *JobStoreError) Unwrap() error {
+	return e.Err
+}
+
+// WithJobStore sets store recording submitted tasks.
+// New resumes polling of every task from store, see ResumedJobs.
+// Image of resumed task is saved to ResultCache if it is set, otherwise task is resumed
+// again after restart until Job.Result returns its image.
+func WithJobStore(s JobStore) Option {
+	return func(k *Kand) {
+		k.store = s
+	}
+}
+
+// ResumedJobs returns jobs resumed from JobStore by New.
+func (k *Kand) ResumedJobs() []*Job {
+	return append([]*Job(nil), k.resumed...)
+}
+
+// resumePending resumes polling of every unfinished task from store
+func (k *Kand) resumePending(ctx context.Context) error {
+	if k.store == nil {
+		return nil
+	}
+
+	records, err := k.store.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		k.resumed = append(k.resumed, k.startJob(&UUID{ID: r.UUID, Status: r.Status, submitted: r.SubmittedAt}, nil))
+	}
+
+	return nil
+}
+
+// keepUnsaved logs and drops err if task u was submitted but not saved to store, so it is polled anyway
+func (k *Kand) keepUnsaved(ctx context.Context, u *UUID, err error) error {
+	var storeErr *JobStoreError
+	if u == nil || !errors.As(err, &storeErr) {
+		return err
+	}
+
+	k.log(withTask(ctx, u.ID), slog.LevelError, "kandinsky job store error", slog.String("error", err.Error()))
+
+	return nil
+}
+
+// saveJob records submitted task in store
+func (k *Kand) saveJob(ctx context.Context, u *UUID, p Params, modelID int) error {
+	if k.store == nil {
+		return nil
+	}
+
+	err := k.store.Put(ctx, JobRecord{
+		UUID:        u.ID,
+		Params:      p,
+		ModelID:     modelID,
+		Status:      u.Status,
+		SubmittedAt: u.submitted,
+		UpdatedAt:   u.submitted,
+	})
+	if err != nil {
+		return &JobStoreError{UUID: u.ID, Err: err}
+	}
+
+	return nil
+}
+
+// updateJob saves status of task polled, tasks not in store are skipped.
+// Store errors are ignored, task is resumed later with older status then.
+func (k *Kand) updateJob(e Event) {
+	if k.store == nil || e.Type != EventStatusChange {
+		return
+	}
+
+	ctx := context.Background()
+	r, err := k.store.Get(ctx, e.UUID)
+	if err != nil {
+		return
+	}
+
+	r.Status = e.Status
+	r.UpdatedAt = k.getClock().Now()
+
+	_ = k.store.Put(ctx, r)
+}
+
+// finishJob deletes record of task polled with result i and err.
+// Record of done task is kept until image is delivered to caller or saved to result cache,
+// record of task stopped by error which is not final is kept to resume it later.
+func (k *Kand) finishJob(uuid string, i *Image, err error, delivered bool) {
+	if k.store == nil {
+		return
+	}
+
+	if err != nil {
+		if isFinal(err) {
+			k.deleteJob(uuid)
+		}
+		return
+	}
+
+	if !delivered {
+		r, err := k.store.Get(context.Background(), uuid)
+		if err != nil || k.cache == nil {
+			return
+		}
+
+		if k.cache.Put(context.Background(), CacheKey(r.Params, r.ModelID), i) != nil {
+			return
+		}
+	}
+
+	k.deleteJob(uuid)
+}
+
+// deleteJob deletes record of task, store errors are ignored
+func (k *Kand) deleteJob(uuid string) {
+	if k.store == nil {
+		return
+	}
+
+	_ = k.store.Delete(context.Background(), uuid)
+}
+
+// isFinal reports whether task failed with err is finished and must not be resumed
+func isFinal(err error) bool {
+	return errors.Is(err, ErrCensored) || errors.Is(err, ErrTaskNotCompleted)
+}
+
+// MemoryJobStore keeps records in memory, e.g. for tests.
+type MemoryJobStore struct {
+	mu      sync.Mutex
+	records map[string]JobRecord
+}
+
+// NewMemoryJobStore creates empty MemoryJobStore.
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{records: make(map[string]JobRecord)}
+}
+
+// Put adds record or replaces record with the same UUID.
+func (s *MemoryJobStore) Put(ctx context.Context, r JobRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[r.UUID] = r
+
+	return nil
+}
+
+// Get returns record by UUID or ErrJobNotFound.
+func (s *MemoryJobStore) Get(ctx context.Context, uuid string) (JobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return JobRecord{}, ErrJobNotFound
+	}
+
+	return r, nil
+}
+
+// Pending returns all records ordered by submit time.
+func (s *MemoryJobStore) Pending(ctx context.Context) ([]JobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return pending(s.records), nil
+}
+
+// Delete removes record by UUID.
+func (s *MemoryJobStore) Delete(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, uuid)
+
+	return nil
+}
+
+// FileJobStore keeps records in json file, file is rewritten atomically on every change.
+// Store is safe for concurrent use within one process.
+type FileJobStore struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]JobRecord
+}
+
+// NewFileJobStore opens FileJobStore at path, file is created on first change.
+func NewFileJobStore(path string) (*FileJobStore, error) {
+	s := &FileJobStore{path: path, records: make(map[string]JobRecord)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > 0 {
+		err = json.Unmarshal(b, &s.records)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Put adds record or replaces record with the same UUID.
+func (s *FileJobStore) Put(ctx context.Context, r JobRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.records[r.UUID]
+	s.records[r.UUID] = r
+
+	err := s.flush()
+	if err != nil {
+		// keep memory in sync with file
+		if ok {
+			s.records[r.UUID] = old
+		} else {
+			delete(s.records, r.UUID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Get returns record by UUID or ErrJobNotFound.
+func (s *FileJobStore) Get(ctx context.Context, uuid string) (JobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return JobRecord{}, ErrJobNotFound
+	}
+
+	return r, nil
+}
+
+// Pending returns all records ordered by submit time.
+func (s *FileJobStore) Pending(ctx context.Context) ([]JobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return pending(s.records), nil
+}
+
+// Delete removes record by UUID.
+func (s *FileJobStore) Delete(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return nil
+	}
+	delete(s.records, uuid)
+
+	err := s.flush()
+	if err != nil {
+		s.records[uuid] = r
+		return err
+	}
+
+	return nil
+}
+
+// flush writes records to temp file and renames it to path
+func (s *FileJobStore) flush() error {
+	b, err := json.MarshalIndent(s.records, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, b)
+}
+
+// pending returns records ordered by submit time
+func pending(records map[string]JobRecord) []JobRecord {
+	p := []JobRecord{}
+	for _, r := range records {
+		p = append(p, r)
+	}
+
+	sort.Slice(p, func(i, j int) bool {
+		return p[i].SubmittedAt.Before(p[j].SubmittedAt)
+	})
+
+	return p
+}
@@ -0,0 +1,278 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestJobStores common test
+func TestJobStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+
+	testCases := []struct {
+		desc string
+		open func() (JobStore, error)
+	}{
+		{
+			desc: "Memory store",
+			open: func() (JobStore, error) { return NewMemoryJobStore(), nil },
+		},
+		{
+			desc: "File store",
+			open: func() (JobStore, error) { return NewFileJobStore(path) },
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			s, err := tC.open()
+			if err != nil {
+				t.Fatalf("open store error > %s", err)
+			}
+
+			start := time.Unix(0, 0)
+			records := []JobRecord{
+				{UUID: "second", Params: params, Status: "PROCESSING", SubmittedAt: start.Add(time.Second)},
+				{UUID: "first", Params: params, Status: "INITIAL", SubmittedAt: start},
+			}
+			for _, r := range records {
+				if err := s.Put(ctx, r); err != nil {
+					t.Fatalf("put error > %s", err)
+				}
+			}
+
+			if err := s.Delete(ctx, "unknown"); err != nil {
+				t.Errorf("delete unknown error > %s", err)
+			}
+
+			if _, err := s.Get(ctx, "unknown"); err != ErrJobNotFound {
+				t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrJobNotFound, err)
+			}
+
+			p, err := s.Pending(ctx)
+			if err != nil {
+				t.Fatalf("pending error > %s", err)
+			}
+
+			got := []string{}
+			for _, r := range p {
+				got = append(got, r.UUID)
+			}
+			if want := []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("\nwant pending:\n\t\t%v \n\tgot:\n\t\t%v\n", want, got)
+			}
+
+			if err := s.Delete(ctx, "first"); err != nil {
+				t.Fatalf("delete error > %s", err)
+			}
+
+			r, err := s.Get(ctx, "second")
+			if err != nil || r.Params.GenerateParams.Query != params.GenerateParams.Query {
+				t.Errorf("\nwant record with params, got:\n\t\t%+v %v\n", r, err)
+			}
+		})
+	}
+
+	// file store must survive reopening
+	s, err := NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("reopen store error > %s", err)
+	}
+
+	p, _ := s.Pending(context.Background())
+	if len(p) != 1 || p[0].UUID != "second" {
+		t.Errorf("\nwant pending after reopen:\n\t\t[second] \n\tgot:\n\t\t%+v\n", p)
+	}
+}
+
+// TestJobStoreResume checks that unfinished task is resumed after restart
+func TestJobStoreResume(t *testing.T) {
+	release := make(chan struct{})
+	ts := (&fakeAPI{status: doneWhen(release)}).start(t)
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	policy := WithPollPolicy(PollPolicy{Interval: time.Millisecond})
+
+	s, err := NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("open store error > %s", err)
+	}
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), policy, WithJobStore(s))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	j, err := k.Submit(context.Background(), params)
+	if err != nil {
+		t.Fatalf("submit error > %s", err)
+	}
+
+	// process stops while task is in progress
+	j.Cancel()
+	j.Wait(context.Background())
+
+	r, err := s.Get(context.Background(), "test-uuid")
+	if err != nil || r.ModelID != 4 || r.Params.GenerateParams.Query != params.GenerateParams.Query {
+		t.Fatalf("\nwant unfinished record, got:\n\t\t%+v %v\n", r, err)
+	}
+
+	// process restarts
+	close(release)
+
+	s, err = NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("reopen store error > %s", err)
+	}
+
+	k, err = New("key", "secret", WithBaseURL(ts.URL), policy, WithJobStore(s))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	jobs := k.ResumedJobs()
+	if len(jobs) != 1 || jobs[0].UUID() != "test-uuid" {
+		t.Fatalf("\nwant resumed job test-uuid, got:\n\t\t%v\n", jobs)
+	}
+
+	i, err := jobs[0].Wait(timeoutContext(t, 5*time.Second))
+	if err != nil || len(i.Images) != 1 {
+		t.Fatalf("\nwant image, got:\n\t\t%v %v\n", i, err)
+	}
+
+	if _, err := s.Get(context.Background(), "test-uuid"); err != ErrJobNotFound {
+		t.Errorf("\nwant collected record deleted:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrJobNotFound, err)
+	}
+}
+
+// TestJobStoreFinish checks when record of resumed task is deleted
+func TestJobStoreFinish(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		status  string
+		cache   bool
+		collect bool
+		kept    bool
+	}{
+		{desc: "Done and collected", status: `"DONE","images":["aGVsbG8="]`, collect: true, kept: false},
+		{desc: "Done and cached", status: `"DONE","images":["aGVsbG8="]`, cache: true, kept: false},
+		{desc: "Done but not collected", status: `"DONE","images":["aGVsbG8="]`, kept: true},
+		{desc: "Failed", status: `"FAIL"`, kept: false},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"uuid":"test-uuid","status":` + tC.status + `}`))
+			}))
+			defer ts.Close()
+
+			s := NewMemoryJobStore()
+			s.Put(context.Background(), JobRecord{UUID: "test-uuid", Params: params, ModelID: 4, Status: "PROCESSING"})
+
+			c := NewMemoryCache(MemoryCacheOptions{})
+			opts := []Option{WithBaseURL(ts.URL), WithJobStore(s)}
+			if tC.cache {
+				opts = append(opts, WithResultCache(c))
+			}
+
+			k, err := New("key", "secret", opts...)
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			j := k.ResumedJobs()[0]
+			<-j.Done()
+			if tC.collect {
+				j.Result()
+			}
+
+			if _, err := s.Get(context.Background(), "test-uuid"); (err == nil) != tC.kept {
+				t.Errorf("\n%s:\n\twant record kept:\n\t\t%t \n\tgot:\n\t\t%v\n", tC.desc, tC.kept, err)
+			}
+
+			if _, ok, _ := c.Get(context.Background(), CacheKey(params, 4)); ok != tC.cache {
+				t.Errorf("\n%s:\n\twant cached:\n\t\t%t \n\tgot:\n\t\t%t\n", tC.desc, tC.cache, ok)
+			}
+		})
+	}
+
+	// package GetImage does not resume tasks from store
+	s := NewMemoryJobStore()
+	s.Put(context.Background(), JobRecord{UUID: "stale-uuid", Params: params, ModelID: 4})
+	k, err := newKand("key", "secret", WithJobStore(s))
+	if err != nil || len(k.ResumedJobs()) != 0 {
+		t.Errorf("\nwant no resumed jobs, got:\n\t\t%v %v\n", k.ResumedJobs(), err)
+	}
+}
+
+// TestJobStoreCheckImage checks that record of task polled by CheckImage is deleted
+func TestJobStoreCheckImage(t *testing.T) {
+	ts := (&fakeAPI{}).start(t)
+	s := NewMemoryJobStore()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithJobStore(s))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	u, err := k.GetImageUUID(params)
+	if err != nil {
+		t.Fatalf("get image uuid error > %s", err)
+	}
+	if _, err := k.CheckImage(u); err != nil {
+		t.Fatalf("check image error > %s", err)
+	}
+
+	if p, err := s.Pending(context.Background()); err != nil || len(p) != 0 {
+		t.Errorf("\nwant no pending records, got:\n\t\t%+v %v\n", p, err)
+	}
+
+	k, err = New("key", "secret", WithBaseURL(ts.URL), WithJobStore(s))
+	if err != nil || len(k.ResumedJobs()) != 0 {
+		t.Errorf("\nwant no resumed jobs, got:\n\t\t%v %v\n", k.ResumedJobs(), err)
+	}
+}
+
+// failingStore fails to save records
+type failingStore struct {
+	*MemoryJobStore
+}
+
+func (s failingStore) Put(ctx context.Context, r JobRecord) error {
+	return errors.New("disk is full")
+}
+
+// TestJobStoreSaveError checks that task is not lost when it can not be saved
+func TestJobStoreSaveError(t *testing.T) {
+	ts := (&fakeAPI{}).start(t)
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithJobStore(failingStore{NewMemoryJobStore()}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	u, err := k.GetImageUUID(params)
+	var storeErr *JobStoreError
+	if !errors.As(err, &storeErr) || u == nil || u.ID != "test-uuid" || storeErr.UUID != "test-uuid" {
+		t.Errorf("\nwant uuid next to store error, got:\n\t\t%+v %v\n", u, err)
+	}
+
+	if i, err := k.GetImage(params); err != nil || i.UUID != "test-uuid" {
+		t.Errorf("\nwant image, got:\n\t\t%v %v\n", i, err)
+	}
+
+	j, err := k.Submit(context.Background(), params)
+	if err != nil {
+		t.Fatalf("submit error > %s", err)
+	}
+	if i, err := j.Wait(timeoutContext(t, 5*time.Second)); err != nil || i.UUID != "test-uuid" {
+		t.Errorf("\nwant image, got:\n\t\t%v %v\n", i, err)
+	}
+}
@@ -2,15 +2,24 @@ package kandinsky
 
 import (
 	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
-	"os/exec"
+	"net/textproto"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -28,14 +37,20 @@ var (
 	ErrUnsupportedMediaType = errors.New("kandinsky is not support format")
 	ErrCensored             = errors.New("kandinsky censored query")
 	ErrBadRequest           = errors.New("kandinsky wrong request parameters or prompt too long ")
+	ErrForbidden            = errors.New("kandinsky access is forbidden")
+	ErrTooManyRequests      = errors.New("kandinsky too many requests")
+	ErrServiceUnavailable   = errors.New("kandinsky service is unavailable")
 )
 
 const (
 	StatusBadRequest           = 400
 	StatusUnauthorized         = 401
+	StatusForbidden            = 403
 	StatusNotFound             = 404
-	StatusInternalServerError  = 500
 	StatusUnsupportedMediaType = 415
+	StatusTooManyRequests      = 429
+	StatusInternalServerError  = 500
+	StatusServiceUnavailable   = 503
 )
 
 // Generate image styles
@@ -50,13 +65,28 @@ const (
 	DEFAULT = "DEFAULT"
 )
 
+// Kandinsky is synchronous Kandinsky API implemented by *Kand, use it to mock the client.
+// Background jobs and batches are methods of *Kand only.
 type Kandinsky interface {
+	ListModels() ([]Model, error)
+	ListModelsContext(ctx context.Context) ([]Model, error)
 	SetModel() (int, error)
+	SetModelContext(ctx context.Context) (int, error)
 	GetImageUUID(p Params) (*UUID, error)
+	GetImageUUIDContext(ctx context.Context, p Params) (*UUID, error)
 	CheckImage(u *UUID) (*Image, error)
+	CheckImageContext(ctx context.Context, u *UUID) (*Image, error)
+	Availability() (*Availability, error)
+	AvailabilityContext(ctx context.Context) (*Availability, error)
+	GetImage(p Params) (*Image, error)
+	GetImageContext(ctx context.Context, p Params) (*Image, error)
+	CurrentModel() Model
 }
 
-// Kand struct, all fields are required
+var _ Kandinsky = (*Kand)(nil)
+
+// Kand struct, all fields are required.
+// Kand created by New is safe for concurrent use and is meant to be reused.
 // https://fusionbrain.ai/docs/ru/doc/api-dokumentaciya/
 type Kand struct {
 	// The API key for authenticating requests to the Kandinsky API.
@@ -69,8 +99,77 @@ type Kand struct {
 	genURL string
 	// Check URL for getting Image instance
 	checkURL string
+	// Availability URL for getting service status.
+	availURL string
+	// HTTP client for all requests to Kandinsky API.
+	client *http.Client
+	// Timeout for every single request, 0 means no timeout.
+	timeout time.Duration
+	// User-Agent header value, empty means Go default.
+	userAgent string
+	// Polling policy for CheckImage.
+	poll PollPolicy
+	// Clock for polling delays and deadlines.
+	clock Clock
+	// Retry policy for failed requests.
+	retry RetryPolicy
+	// Selectors to choose model from ListModels.
+	selectors []ModelSelector
+	// Pinned model version, 0 means any.
+	version float32
+	// What GetImage does when service is unavailable.
+	availMode AvailabilityMode
+	// Polling policy for waiting service availability.
+	availPoll PollPolicy
+	// Validate params before sending.
+	validate bool
+	// Receives polling events of every task.
+	observer Observer
+	// Client side limits.
+	rate RateLimit
+	// Limiter built from rate, nil means no limits.
+	limiter *limiter
+	// Shared poller options, nil means every task is polled by own loop.
+	sharedPoll *SharedPollerOptions
+	// Shared poller built from sharedPoll.
+	poller *poller
+	// Records submitted tasks, nil means no recording.
+	store JobStore
+	// Jobs resumed from store by New.
+	resumed []*Job
+	// Provider of key and secret, nil means key and secret fields are used.
+	creds CredentialsProvider
+	// Generated images by params, nil means no caching.
+	cache ResultCache
+	// Concurrent GetImage calls with identical params do not share task.
+	noDedup bool
+	// Coalesces concurrent GetImage calls with identical params.
+	flight flightGroup
+	// How long models list is cached, 0 means no caching.
+	modelTTL time.Duration
+	// Logs requests, polls and errors, nil means no logging.
+	logger *slog.Logger
+	// Providers of tracer and meter, nil means no telemetry.
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	// Tracer and instruments built from providers.
+	tel *telemetry
+	// Wrap transport of client.
+	middlewares []Middleware
+
+	// Guards Model and models cache.
+	mu sync.RWMutex
+	// Serializes models requests so parallel callers share one.
+	listMu sync.Mutex
+	// Cached models list.
+	models []Model
+	// When models list was received.
+	modelsAt time.Time
+	// When Model was selected by SetModel, zero if Model was set directly.
+	selectedAt time.Time
 
 	// The current Model selected for generating images, represented by the Model structure.
+	// Use CurrentModel to read it while Kand is in use.
 	Model Model
 }
 
@@ -127,9 +226,17 @@ type Params struct {
 	// Negative prompts to avoid in the image generation.
 	NegativePrompt string `json:"negativePromptUnclip"`
 	// Parameters for the generation, including the Query for the image content.
-	GenerateParams struct {
-		Query string `json:"query"`
-	} `json:"generateParams"`
+	GenerateParams GenerateParams `json:"generateParams"`
+}
+
+// GenerateParams is the generateParams block of Params
+//
+//	{
+//		"query": "Пушистый кот в очках"
+//	}
+type GenerateParams struct {
+	// Requirement prompt to generate image.
+	Query string `json:"query"`
 }
 
 // UUID response with UUID from Kandinsky API
@@ -143,6 +250,9 @@ type UUID struct {
 	ID string `json:"uuid"`
 	// The status of the task, e.g., "INITIAL".
 	Status string `json:"status"`
+
+	// When task was submitted, zero if unknown.
+	submitted time.Time
 }
 
 // ErrResponse from Kandinsky API
@@ -168,29 +278,88 @@ type ErrResponse struct {
 }
 
 // New creates a new instance of the Kandinsky client.
-func New(key, secret string) (Kandinsky, error) {
-	if key == "" {
-		return nil, ErrEmptyKey
+// Options are applied in order, see WithHTTPClient, WithBaseURL, WithTimeout and WithUserAgent.
+func New(key, secret string, opts ...Option) (*Kand, error) {
+	k, err := newKand(key, secret, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	if secret == "" {
-		return nil, ErrEmptySecret
+	// resumed by long-lived client only, GetImage would start pollers nobody collects
+	err = k.resumePending(context.Background())
+	if err != nil {
+		return nil, err
 	}
 
+	return k, nil
+}
+
+// newKand creates Kand instance with options applied
+func newKand(key, secret string, opts ...Option) (*Kand, error) {
 	k := &Kand{
 		key:      key,
 		secret:   secret,
-		authURL:  "https://api-key.fusionbrain.ai/key/api/v1/models",
-		genURL:   "https://api-key.fusionbrain.ai/key/api/v1/text2image/run",
-		checkURL: "https://api-key.fusionbrain.ai/key/api/v1/text2image/status/",
+		client:   &http.Client{},
+		poll:     DefaultPollPolicy(),
+		retry:    DefaultRetryPolicy(),
+		clock:    realClock{},
+		modelTTL: DefaultModelTTL,
 		Model:    Model{},
 	}
+	k.setBaseURL(DefaultBaseURL)
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	// key and secret are required without provider
+	if k.creds == nil {
+		err := Credentials{Key: key, Secret: secret}.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if k.authURL == "" || k.genURL == "" || k.checkURL == "" || k.availURL == "" {
+		return nil, ErrEmptyURL
+	}
+
+	tel, err := newTelemetry(k.tracerProvider, k.meterProvider)
+	if err != nil {
+		return nil, err
+	}
+	k.tel = tel
+
+	k.limiter = newLimiter(k.rate, k.getClock())
+	if k.sharedPoll != nil {
+		k.poller = newPoller(k, *k.sharedPoll)
+	}
+
+	// copy client to not modify shared one
+	if k.timeout > 0 || len(k.middlewares) > 0 {
+		c := *k.client
+		if k.timeout > 0 {
+			c.Timeout = k.timeout
+		}
+		if len(k.middlewares) > 0 {
+			c.Transport = chain(c.Transport, k.middlewares)
+		}
+		k.client = &c
+	}
 
 	return k, nil
 }
 
-// GetImage return Image struct, generated by Kandinsky API
-func GetImage(key, secret string, params Params) (*Image, error) {
+// GetImage return Image struct, generated by Kandinsky API.
+// Options are the same as for New, see WithAvailabilityCheck to check service before submitting task.
+// Without options client is shared between calls with the same key and secret,
+// use New and Kand.GetImage to control client lifetime.
+func GetImage(key, secret string, params Params, opts ...Option) (*Image, error) {
+	return GetImageContext(context.Background(), key, secret, params, opts...)
+}
+
+// GetImageContext is like GetImage but cancels requests and polling when ctx is done.
+func GetImageContext(ctx context.Context, key, secret string, params Params, opts ...Option) (*Image, error) {
 	i := new(Image)
 	if key == "" {
 		return nil, ErrEmptyKey
@@ -204,31 +373,143 @@ func GetImage(key, secret string, params Params) (*Image, error) {
 		return nil, ErrEmptyPrompt
 	}
 
-	k, err := New(key, secret)
+	var k *Kand
+	var err error
+	if len(opts) == 0 {
+		k, err = sharedKand(key, secret)
+	} else {
+		k, err = newKand(key, secret, opts...)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = k.SetModel()
+	i, err = k.GetImageContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
-	u, err := k.GetImageUUID(params)
+	return i, nil
+}
+
+// MaxSharedClients is how many clients package level GetImage keeps for reuse,
+// the least recently used one is dropped when credentials rotate.
+const MaxSharedClients = 16
+
+// sharedClients keeps clients of package level GetImage by hash of key and secret
+type sharedClients struct {
+	mu sync.Mutex
+	// Recently used first, values are *sharedClient.
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// sharedClient is client kept with hash of its credentials
+type sharedClient struct {
+	id string
+	k  *Kand
+}
+
+// clients shared by GetImage calls without options
+var clients = &sharedClients{ll: list.New(), items: make(map[string]*list.Element)}
+
+// sharedKand returns client shared by GetImage calls with key and secret
+func sharedKand(key, secret string) (*Kand, error) {
+	return clients.get(key, secret)
+}
+
+// get returns client for key and secret, creating it if it is not kept
+func (c *sharedClients) get(key, secret string) (*Kand, error) {
+	// secret is not kept in plain text
+	sum := sha256.Sum256([]byte(key + "\x00" + secret))
+	id := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*sharedClient).k, nil
+	}
+
+	k, err := newKand(key, secret)
 	if err != nil {
 		return nil, err
 	}
 
-	i, err = k.CheckImage(u)
+	c.items[id] = c.ll.PushFront(&sharedClient{id: id, k: k})
+	for c.ll.Len() > MaxSharedClients {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*sharedClient).id)
+	}
+
+	return k, nil
+}
+
+// GetImage generates image with params p and waits until it is done.
+// Model and connections are reused between calls, so Kand may serve many parallel generations.
+func (k *Kand) GetImage(p Params) (*Image, error) {
+	return k.GetImageContext(context.Background(), p)
+}
+
+// GetImageContext is like GetImage but cancels requests and polling when ctx is done.
+func (k *Kand) GetImageContext(ctx context.Context, p Params) (*Image, error) {
+	if p.GenerateParams.Query == "" {
+		return nil, ErrEmptyPrompt
+	}
+
+	m, err := k.ensureModel(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	key := CacheKey(p, m.ID)
+	if i, ok := k.cachedImage(ctx, key); ok {
+		return i, nil
+	}
+
+	if k.noDedup {
+		return k.generate(ctx, p, key)
+	}
+
+	return k.flight.do(ctx, key, func(ctx context.Context) (*Image, error) {
+		return k.generate(ctx, p, key)
+	})
+}
+
+// generate submits task, waits for image and saves it to cache by key
+func (k *Kand) generate(ctx context.Context, p Params, key string) (*Image, error) {
+	err := k.checkAvailability(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = k.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer k.limiter.release()
+
+	u, err := k.GetImageUUIDContext(ctx, p)
+	err = k.keepUnsaved(ctx, u, err)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := k.CheckImageContext(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	k.cacheImage(ctx, key, i)
+
 	return i, nil
 }
 
 // SetModel sets the model to be used by the Kandinsky client. Return model ID.
-// Send auth request to url and set image UUID to Kandinsky instance from json response:
+// Model is chosen from ListModels by selectors set with WithModelSelector, first one by default.
+// Send auth request to url and set model to Kandinsky instance from json response:
 // [
 //
 //	{
@@ -240,56 +521,65 @@ func GetImage(key, secret string, params Params) (*Image, error) {
 //
 // ]
 func (k *Kand) SetModel() (int, error) {
-	// create GET request, set auth headers
-	req, err := http.NewRequest(http.MethodGet, k.authURL, nil)
-	if err != nil {
-		return 0, err
-	}
-	req.Header.Add("X-Key", "Key "+k.key)
-	req.Header.Add("X-Secret", "Secret "+k.secret)
+	return k.SetModelContext(context.Background())
+}
 
-	// create client and do request to Kandinsky API
-	client := http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer res.Body.Close()
+// SetModelContext is like SetModel but uses ctx for the request.
+func (k *Kand) SetModelContext(ctx context.Context) (id int, err error) {
+	ctx, span := k.getTelemetry().start(ctx, "kandinsky.SetModel")
+	defer func() {
+		span.SetAttributes(attribute.Int("kandinsky.model_id", id))
+		endSpan(span, err)
+	}()
 
-	// check status code from received from API
-	err = checkStatusCode(res.StatusCode)
+	m, err := k.ListModelsContext(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	// unmarshal response
-	m := []Model{}
-	b, err := io.ReadAll(res.Body)
+	model, err := SelectModel(m, k.selectors...)
 	if err != nil {
 		return 0, err
 	}
-	err = json.Unmarshal(b, &m)
-	if err != nil {
-		return 0, err
+
+	if k.version != 0 && model.Version != k.version {
+		return 0, &ModelVersionError{Want: k.version, Got: model}
 	}
 
-	k.Model = m[0]
+	k.mu.Lock()
+	k.Model = model
+	k.selectedAt = k.getClock().Now()
+	k.mu.Unlock()
 
-	return k.Model.ID, nil
+	return model.ID, nil
 }
 
 // GetImageUUID sends a POST request with parameters to generate an image and returns the UUID.
+// If task can not be saved to JobStore, UUID is returned next to *JobStoreError.
 //
 //	{
 //		"uuid": "string",
 //		"status": "INITIAL"
 //	}
 func (k *Kand) GetImageUUID(p Params) (*UUID, error) {
+	return k.GetImageUUIDContext(context.Background(), p)
+}
+
+// GetImageUUIDContext is like GetImageUUID but uses ctx for the request.
+func (k *Kand) GetImageUUIDContext(ctx context.Context, p Params) (_ *UUID, err error) {
 	u := new(UUID)
 
-	// set default
-	if k.Model.ID == 0 {
-		k.Model.ID = 4
+	t := k.getTelemetry()
+	ctx, span := t.start(ctx, "kandinsky.GetImageUUID")
+	defer func() {
+		span.SetAttributes(attribute.String("kandinsky.uuid", u.ID))
+		endSpan(span, err)
+	}()
+
+	// set model if not set yet
+	m, err := k.ensureModel(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	setDefaultParams(&p)
@@ -299,51 +589,92 @@ func (k *Kand) GetImageUUID(p Params) (*UUID, error) {
 		return nil, ErrEmptyPrompt
 	}
 
+	if k.validate {
+		err := p.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// marshall params to json bytes
 	b, err := json.Marshal(&p)
 	if err != nil {
 		return nil, err
 	}
 
-	// generate command string
-	curlCommand := fmt.Sprintf(`curl --location --request POST 'https://api-key.fusionbrain.ai/key/api/v1/text2image/run' --header 'X-Key: Key %s' --header 'X-Secret: Secret %s' -F 'params=%s
-	};type=application/json' --form 'model_id="%d"'`, k.key, k.secret, string(b), k.Model.ID)
+	// build multipart body with json params part and model id field
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
 
-	// create command
-	cmd := exec.Command("sh", "-c", curlCommand)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="params"`)
+	h.Set("Content-Type", "application/json")
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	_, err = part.Write(b)
+	if err != nil {
+		return nil, err
+	}
 
-	// buffers for standard out and error
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
+	err = w.WriteField("model_id", strconv.Itoa(m.ID))
+	if err != nil {
+		return nil, err
+	}
 
-	// run command
-	err = cmd.Run()
+	err = w.Close()
 	if err != nil {
-		fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
 		return nil, err
 	}
 
-	// out to string
-	s := out.String()
-	// if response status not 200
-	if strings.Contains(s, "error") {
-		e := ErrResponse{}
-		err = json.Unmarshal(out.Bytes(), &e)
+	// do POST request with auth headers to Kandinsky API,
+	// not idempotent as repeated request may create one more paid task
+	ctx = withEndpoint(ctx, EndpointRun)
+	res, err := k.do(ctx, func() (*http.Request, error) {
+		req, err := k.newRequest(ctx, http.MethodPost, k.genURL, bytes.NewReader(body.Bytes()))
 		if err != nil {
 			return nil, err
 		}
+		req.Header.Set("Content-Type", w.FormDataContentType())
 
-		return nil, errors.New("error from Kandinsky API: status " + strconv.Itoa(e.Status) + " " + e.Error + " > " + e.Message)
+		return req, nil
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err = io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
 	}
 
 	// unmarshal out data to UUID struct
-	err = json.Unmarshal(out.Bytes(), &u)
+	err = json.Unmarshal(b, &u)
 	if err != nil {
 		return nil, err
 	}
 
+	// service responds with availability status instead of task when queue is disabled
+	if u.ID == "" {
+		a := new(Availability)
+		if json.Unmarshal(b, a) == nil && !a.Available() {
+			return nil, &UnavailableError{Status: a.status()}
+		}
+	}
+
+	u.submitted = k.getClock().Now()
+	span.SetAttributes(attribute.Int("kandinsky.model_id", m.ID))
+	t.submissions.Add(ctx, 1)
+	k.log(withTask(ctx, u.ID), slog.LevelInfo, "kandinsky task submitted", slog.String("status", u.Status), slog.Int("model_id", m.ID))
+
+	// task is created and paid for, so UUID is returned even if it is not saved
+	err = k.saveJob(ctx, u, p, m.ID)
+	if err != nil {
+		return u, err
+	}
+
 	return u, nil
 }
 
@@ -356,59 +687,182 @@ func (k *Kand) GetImageUUID(p Params) (*UUID, error) {
 //	   "censored": false
 //		}
 func (k *Kand) CheckImage(u *UUID) (*Image, error) {
-	image := new(Image)
+	return k.CheckImageContext(context.Background(), u)
+}
+
+// CheckImageContext is like CheckImage but stops polling and returns ctx error when ctx is done.
+// Polling follows policy set by WithPollPolicy, *PollTimeoutError is returned when it gives up.
+func (k *Kand) CheckImageContext(ctx context.Context, u *UUID) (*Image, error) {
+	i, err := k.pollImage(ctx, u, nil)
+	// image is handed to caller, so task record is not needed anymore
+	k.finishJob(u.ID, i, err, true)
 
+	return i, err
+}
+
+// pollImage polls task status following poll policy, onEvent and observer receive every event
+func (k *Kand) pollImage(ctx context.Context, u *UUID, onEvent Observer) (image *Image, err error) {
 	if u.ID == "" {
 		return nil, ErrEmptyUUID
 	}
 
-	for {
-		// create GET request
-		req, err := http.NewRequest(http.MethodGet, k.checkURL+u.ID, nil)
-		if err != nil {
-			return nil, err
+	p := k.poll.withDefaults()
+
+	c := k.getClock()
+	start := c.Now()
+	delay := p.InitialDelay
+	interval := p.Interval
+	status := u.Status
+	polls := 0
+	t := k.getTelemetry()
+	// when status was received first
+	since := start
+
+	emit := func(e Event) {
+		e.UUID, e.Status, e.Attempt, e.Elapsed = u.ID, status, polls, c.Now().Sub(start)
+		k.updateJob(e)
+		k.logEvent(ctx, e)
+		if onEvent != nil {
+			onEvent(e)
 		}
-
-		// create auth header
-		req.Header.Add("X-Key", "Key "+k.key)
-		req.Header.Add("X-Secret", "Secret "+k.secret)
-
-		// create client
-		client := http.Client{}
-
-		// Do request to Kandinsky API
-		res, err := client.Do(req)
-		if err != nil {
-			return nil, err
+		if k.observer != nil {
+			k.observer(e)
 		}
-
-		// check status code from received from API
-		err = checkStatusCode(res.StatusCode)
-		if err != nil {
-			return nil, err
+	}
+	defer func() {
+		emit(Event{Type: EventDone, Err: err})
+	}()
+
+	for attempt := 1; ; attempt++ {
+		// wait, but not longer than overall deadline
+		if p.Timeout > 0 {
+			left := p.Timeout - c.Now().Sub(start)
+			if left <= 0 {
+				return nil, &PollTimeoutError{UUID: u.ID, Status: status, Attempts: polls, Elapsed: c.Now().Sub(start)}
+			}
+			if delay > left {
+				delay = left
+			}
 		}
 
-		b, err := io.ReadAll(res.Body)
+		image, err := k.pollOnce(ctx, c, u.ID, delay)
 		if err != nil {
-			return nil, err
+			return nil, &PollError{UUID: u.ID, Status: status, Attempts: polls, Err: err}
 		}
-
-		err = json.Unmarshal(b, &image)
-		if err != nil {
-			return nil, err
+		polls = attempt
+
+		prev := status
+		status = image.Status
+		emit(Event{Type: EventPoll})
+		if status != prev {
+			emit(Event{Type: EventStatusChange, PrevStatus: prev})
+			now := c.Now()
+			if prev != "" {
+				t.statusDuration.Record(ctx, now.Sub(since).Seconds(), metric.WithAttributes(attribute.String("status", prev)))
+			}
+			since = now
 		}
 
 		if image.Status == "DONE" {
 			if image.Censored {
+				t.completed(ctx, CompletionCensored, u.submitted, c.Now())
 				return nil, ErrCensored
 			}
+			t.completed(ctx, CompletionDone, u.submitted, c.Now())
 			return image, nil
 		} else if image.Status == "FAIL" {
+			t.completed(ctx, CompletionFail, u.submitted, c.Now())
 			return nil, ErrTaskNotCompleted
 		}
 
-		time.Sleep(time.Second * 10)
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return nil, &PollTimeoutError{UUID: u.ID, Status: status, Attempts: polls, Elapsed: c.Now().Sub(start)}
+		}
+
+		delay = interval
+		interval = p.next(interval)
+	}
+}
+
+// pollOnce checks status of task id after delay, by shared poller if it is set
+func (k *Kand) pollOnce(ctx context.Context, c Clock, id string, delay time.Duration) (*Image, error) {
+	if k.poller != nil {
+		return k.poller.check(ctx, id, delay)
+	}
+
+	err := sleep(ctx, c, delay)
+	if err != nil {
+		return nil, err
 	}
+
+	return k.checkStatus(ctx, id)
+}
+
+// checkStatus does single status request for task id
+func (k *Kand) checkStatus(ctx context.Context, id string) (_ *Image, err error) {
+	image := new(Image)
+	ctx = withEndpoint(withTask(ctx, id), EndpointStatus)
+
+	t := k.getTelemetry()
+	ctx, span := t.start(ctx, "kandinsky.CheckImage", attribute.String("kandinsky.uuid", id))
+	defer func() {
+		span.SetAttributes(attribute.String("kandinsky.status", image.Status))
+		endSpan(span, err)
+		if err == nil {
+			t.polls.Add(ctx, 1, metric.WithAttributes(attribute.String("status", image.Status)))
+		}
+	}()
+
+	// do GET request with auth headers to Kandinsky API
+	res, err := k.do(ctx, func() (*http.Request, error) {
+		return k.newRequest(ctx, http.MethodGet, k.checkURL+id, nil)
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, image)
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// newRequest creates request to Kandinsky API with auth and User-Agent headers
+func (k *Kand) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	c, err := k.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("X-Key", "Key "+c.Key)
+	req.Header.Add("X-Secret", "Secret "+c.Secret)
+	if k.userAgent != "" {
+		req.Header.Set("User-Agent", k.userAgent)
+	}
+
+	return req, nil
+}
+
+// httpClient returns client for requests, default client if Kand was created without New
+func (k *Kand) httpClient() *http.Client {
+	if k.client == nil {
+		return http.DefaultClient
+	}
+
+	return k.client
 }
 
 // checkStatusCode check response code from kandinsky
@@ -418,15 +872,25 @@ func checkStatusCode(code int) error {
 		return ErrBadRequest
 	case StatusUnauthorized:
 		return ErrUnauthorized
+	case StatusForbidden:
+		return ErrForbidden
 	case StatusNotFound:
 		return ErrNotFound
-	case StatusInternalServerError:
-		return ErrInternalServerError
 	case StatusUnsupportedMediaType:
 		return ErrUnsupportedMediaType
-	default:
-		return nil
+	case StatusTooManyRequests:
+		return ErrTooManyRequests
+	case StatusInternalServerError:
+		return ErrInternalServerError
+	case StatusServiceUnavailable:
+		return ErrServiceUnavailable
 	}
+
+	if code < 200 || code > 299 {
+		return ErrStatusNot200
+	}
+
+	return nil
 }
 
 // setDefaultParams set empty params
@@ -5,9 +5,17 @@ package kandinsky
 // KAND_API_SECRET=your_secret
 
 import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -294,6 +302,105 @@ func TestGetImageUUID(t *testing.T) {
 	}
 }
 
+// TestGetImageUUIDMultipart checks multipart body sent to run endpoint
+func TestGetImageUUIDMultipart(t *testing.T) {
+	query := "cat's \"glasses\""
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Key") != "Key test-key" || r.Header.Get("X-Secret") != "Secret test-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("multipart reader error > %s", err)
+			return
+		}
+
+		p := Params{}
+		modelID := ""
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+
+			switch part.FormName() {
+			case "params":
+				if ct := part.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("params content type:\n\twant:\n\t\t\"application/json\" \n\tgot:\n\t\t\"%s\"\n", ct)
+				}
+				if err := json.NewDecoder(part).Decode(&p); err != nil {
+					t.Errorf("decode params error > %s", err)
+				}
+			case "model_id":
+				b, _ := io.ReadAll(part)
+				modelID = string(b)
+			}
+		}
+
+		if modelID != "4" {
+			t.Errorf("model_id:\n\twant:\n\t\t\"4\" \n\tgot:\n\t\t\"%s\"\n", modelID)
+		}
+
+		if p.GenerateParams.Query != query {
+			t.Errorf("query:\n\twant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%s\"\n", query, p.GenerateParams.Query)
+		}
+
+		if p.Style == "WRONG" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"error":"Bad Request","message":"wrong style"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+	}))
+	defer ts.Close()
+
+	k := &Kand{key: "test-key", secret: "test-secret", genURL: ts.URL, Model: Model{ID: 4}}
+
+	testCases := []struct {
+		desc  string
+		style string
+		want  string
+	}{
+		{
+			desc:  "Successful GetImageUUID",
+			style: KANDINSKY,
+			want:  "",
+		},
+		{
+			desc:  "Error response",
+			style: "WRONG",
+			want:  "status 400 Bad Request > wrong style",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := params
+			p.Style = tC.style
+			p.GenerateParams.Query = query
+
+			u, err := k.GetImageUUID(p)
+			if tC.want == "" {
+				if err != nil {
+					t.Fatalf("\n%s: unexpected error > %s", tC.desc, err)
+				}
+				if u.ID != "test-uuid" {
+					t.Errorf("\n%s:\n\twant:\n\t\t\"test-uuid\" \n\tgot:\n\t\t\"%s\"\n", tC.desc, u.ID)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tC.want) {
+				t.Errorf("\n%s:\n\twant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+		})
+	}
+}
+
 // TestCheckImage common test
 func TestCheckImage(t *testing.T) {
 	k, err := New(key, secret)
@@ -482,3 +589,87 @@ func TestGetImage(t *testing.T) {
 		})
 	}
 }
+
+// TestCheckImageContext checks that polling stops when context is done
+func TestCheckImageContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"uuid":"test-uuid","status":"PROCESSING"}`))
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		desc string
+		ctx  context.Context
+		want error
+	}{
+		{
+			desc: "Deadline while polling",
+			ctx:  timeoutContext(t, 50*time.Millisecond),
+			want: context.DeadlineExceeded,
+		},
+		{
+			desc: "Canceled before request",
+			ctx:  canceled,
+			want: context.Canceled,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			start := time.Now()
+
+			_, err := k.CheckImageContext(tC.ctx, &UUID{ID: "test-uuid"})
+			if !errors.Is(err, tC.want) {
+				t.Errorf("\n%s:\n\twant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if time.Since(start) > time.Second {
+				t.Errorf("\n%s: polling was not interrupted", tC.desc)
+			}
+		})
+	}
+}
+
+// timeoutContext returns context canceled after d or at the end of test
+func timeoutContext(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// TestSharedClients checks that clients of package level GetImage are bounded and keyed by hash
+func TestSharedClients(t *testing.T) {
+	c := &sharedClients{ll: list.New(), items: make(map[string]*list.Element)}
+
+	first, err := c.get("key", "secret-0")
+	if err != nil {
+		t.Fatalf("get client error > %s", err)
+	}
+	if again, _ := c.get("key", "secret-0"); again != first {
+		t.Errorf("\nwant client reused for the same credentials\n")
+	}
+
+	// rotated credentials push out the least recently used client
+	for i := 1; i <= MaxSharedClients; i++ {
+		c.get("key", "secret-"+strconv.Itoa(i))
+	}
+
+	if c.ll.Len() != MaxSharedClients {
+		t.Errorf("\nwant clients:\n\t\t%d \n\tgot:\n\t\t%d\n", MaxSharedClients, c.ll.Len())
+	}
+	if again, _ := c.get("key", "secret-0"); again == first {
+		t.Errorf("\nwant least recently used client dropped\n")
+	}
+	for id := range c.items {
+		if strings.Contains(id, "secret") {
+			t.Errorf("\nwant secret not kept in key, got:\n\t\t%q\n", id)
+		}
+	}
+}
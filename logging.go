@@ -0,0 +1,88 @@
+package kandinsky
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithLogger sets logger for HTTP calls, retries, polls, status changes and errors.
+// Request attempts and polls are logged at Debug level, submissions and status changes at Info,
+// retries at Warn and final errors once at Error, errors of status requests as task errors.
+// nil means no logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(k *Kand) {
+		k.logger = l
+	}
+}
+
+// taskKey is context key for UUID of the task request belongs to
+type taskKey struct{}
+
+// withTask returns ctx carrying task UUID
+func withTask(ctx context.Context, uuid string) context.Context {
+	return context.WithValue(ctx, taskKey{}, uuid)
+}
+
+// taskFrom returns task UUID carried by ctx, empty if none
+func taskFrom(ctx context.Context) string {
+	uuid, _ := ctx.Value(taskKey{}).(string)
+	return uuid
+}
+
+// log writes record if logger is set
+func (k *Kand) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if k.logger == nil || !k.logger.Enabled(ctx, level) {
+		return
+	}
+
+	if uuid := taskFrom(ctx); uuid != "" {
+		attrs = append(attrs, slog.String("uuid", uuid))
+	}
+
+	k.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logRequest logs single attempt of request
+func (k *Kand) logRequest(ctx context.Context, req *http.Request, res *http.Response, err error, attempt int, latency time.Duration) {
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("endpoint", req.URL.Redacted()),
+		slog.Int("attempt", attempt),
+		slog.Duration("latency", latency),
+	}
+	if res != nil {
+		attrs = append(attrs, slog.Int("status_code", res.StatusCode))
+	}
+
+	if err != nil {
+		k.log(ctx, slog.LevelDebug, "kandinsky request failed", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+
+	k.log(ctx, slog.LevelDebug, "kandinsky request", attrs...)
+}
+
+// logEvent logs polling event
+func (k *Kand) logEvent(ctx context.Context, e Event) {
+	ctx = withTask(ctx, e.UUID)
+	attrs := []slog.Attr{
+		slog.String("status", e.Status),
+		slog.Int("attempt", e.Attempt),
+		slog.Duration("elapsed", e.Elapsed),
+	}
+
+	switch e.Type {
+	case EventPoll:
+		k.log(ctx, slog.LevelDebug, "kandinsky poll", attrs...)
+	case EventStatusChange:
+		k.log(ctx, slog.LevelInfo, "kandinsky status changed", append(attrs, slog.String("prev_status", e.PrevStatus))...)
+	case EventDone:
+		if e.Err != nil {
+			k.log(ctx, slog.LevelError, "kandinsky task failed", append(attrs, slog.String("error", e.Err.Error()))...)
+			return
+		}
+		k.log(ctx, slog.LevelInfo, "kandinsky task done", attrs...)
+	}
+}
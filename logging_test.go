@@ -0,0 +1,166 @@
+package kandinsky
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is bytes.Buffer safe for concurrent writes
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// TestWithLogger checks records logged for generation with one retried status request
+func TestWithLogger(t *testing.T) {
+	var statuses int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case modelsPath:
+			w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+		case runPath:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+		default:
+			statuses++
+			if statuses == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+		}
+	}))
+	defer ts.Close()
+
+	out := new(syncBuffer)
+	l := slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithLogger(l),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	if _, err := k.GetImage(params); err != nil {
+		t.Fatalf("get image error > %s", err)
+	}
+
+	type record struct {
+		Level      string `json:"level"`
+		Msg        string `json:"msg"`
+		UUID       string `json:"uuid"`
+		Endpoint   string `json:"endpoint"`
+		StatusCode int    `json:"status_code"`
+		Latency    *int64 `json:"latency"`
+	}
+
+	var records []record
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("decode record %q error > %s", line, err)
+		}
+		records = append(records, r)
+	}
+
+	testCases := []struct {
+		desc   string
+		record record
+	}{
+		{
+			desc:   "Models request",
+			record: record{Level: "DEBUG", Msg: "kandinsky request", Endpoint: ts.URL + modelsPath, StatusCode: 200},
+		},
+		{
+			desc:   "Submission",
+			record: record{Level: "INFO", Msg: "kandinsky task submitted", UUID: "test-uuid"},
+		},
+		{
+			desc:   "Failed status request",
+			record: record{Level: "DEBUG", Msg: "kandinsky request failed", UUID: "test-uuid", Endpoint: ts.URL + statusPath + "test-uuid", StatusCode: 503},
+		},
+		{
+			desc:   "Retry",
+			record: record{Level: "WARN", Msg: "kandinsky retrying request", UUID: "test-uuid", Endpoint: ts.URL + statusPath + "test-uuid"},
+		},
+		{
+			desc:   "Poll",
+			record: record{Level: "DEBUG", Msg: "kandinsky poll", UUID: "test-uuid"},
+		},
+		{
+			desc:   "Status change",
+			record: record{Level: "INFO", Msg: "kandinsky status changed", UUID: "test-uuid"},
+		},
+		{
+			desc:   "Done",
+			record: record{Level: "INFO", Msg: "kandinsky task done", UUID: "test-uuid"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			for _, r := range records {
+				if r.Msg == tC.record.Msg && r.Level == tC.record.Level && r.UUID == tC.record.UUID &&
+					(tC.record.Endpoint == "" || r.Endpoint == tC.record.Endpoint) &&
+					(tC.record.StatusCode == 0 || r.StatusCode == tC.record.StatusCode) {
+					if r.Msg == "kandinsky request" && r.Latency == nil {
+						t.Errorf("\n%s: latency is not logged\n", tC.desc)
+					}
+					return
+				}
+			}
+			t.Errorf("\n%s:\n\twant record:\n\t\t%+v \n\tgot:\n\t\t%s\n", tC.desc, tC.record, out)
+		})
+	}
+}
+
+// TestWithLoggerFinalError checks that error of given up status request is logged once
+func TestWithLoggerFinalError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	out := new(syncBuffer)
+	l := slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithLogger(l), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	if _, err := k.CheckImage(&UUID{ID: "test-uuid"}); err == nil {
+		t.Fatalf("want check image error")
+	}
+
+	levels := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r struct {
+			Level string `json:"level"`
+		}
+		json.Unmarshal([]byte(line), &r)
+		levels[r.Level]++
+	}
+
+	if levels["ERROR"] != 1 || levels["WARN"] != 0 {
+		t.Errorf("\nwant one error record and no warnings, got:\n\t\t%s\n", out)
+	}
+}
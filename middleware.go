@@ -0,0 +1,179 @@
+package kandinsky
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// EndpointKind names Kandinsky API endpoint request is sent to.
+type EndpointKind string
+
+// Kandinsky API endpoints
+const (
+	// Models list
+	EndpointModels EndpointKind = "models"
+	// Task submission
+	EndpointRun EndpointKind = "run"
+	// Task status
+	EndpointStatus EndpointKind = "status"
+	// Service availability
+	EndpointAvailability EndpointKind = "availability"
+)
+
+// Middleware wraps transport used for all requests to Kandinsky API.
+// Use RequestEndpoint and RequestTaskUUID to get endpoint and task of request.
+// Like any http.RoundTripper it must not modify request, clone it instead.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is function implementing http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware adds middlewares wrapping transport of HTTP client, the first one is the outermost.
+// Every retry attempt passes middlewares. Client set by WithHTTPClient is copied, not modified.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(k *Kand) {
+		k.middlewares = append(k.middlewares, mw...)
+	}
+}
+
+// chain wraps rt by middlewares, the first one is the outermost
+func chain(rt http.RoundTripper, mw []Middleware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		rt = mw[i](rt)
+	}
+
+	return rt
+}
+
+// endpointKey is context key for endpoint request is sent to
+type endpointKey struct{}
+
+// withEndpoint returns ctx carrying endpoint kind
+func withEndpoint(ctx context.Context, e EndpointKind) context.Context {
+	return context.WithValue(ctx, endpointKey{}, e)
+}
+
+// endpointFrom returns endpoint kind carried by ctx, empty if none
+func endpointFrom(ctx context.Context) EndpointKind {
+	e, _ := ctx.Value(endpointKey{}).(EndpointKind)
+	return e
+}
+
+// RequestEndpoint returns endpoint request is sent to, empty if request was not sent by Kand.
+func RequestEndpoint(req *http.Request) EndpointKind {
+	return endpointFrom(req.Context())
+}
+
+// RequestTaskUUID returns UUID of the task request is about, empty for models, run and availability requests.
+func RequestTaskUUID(req *http.Request) string {
+	return taskFrom(req.Context())
+}
+
+// RoundTrip describes finished request to Kandinsky API.
+type RoundTrip struct {
+	// Endpoint request was sent to.
+	Endpoint EndpointKind
+	// UUID of the task, empty if request is not about task.
+	UUID string
+	// HTTP method of request.
+	Method string
+	// HTTP status code, 0 if request failed.
+	StatusCode int
+	// Time from sending request to receiving response headers.
+	Duration time.Duration
+	// Transport error.
+	Err error
+}
+
+// TimingMiddleware calls fn after every request with its endpoint, task, status and duration.
+func TimingMiddleware(fn func(rt RoundTrip)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res, rt := timedRoundTrip(next, req)
+			fn(rt)
+
+			return res, rt.Err
+		})
+	}
+}
+
+// timedRoundTrip sends req by next and describes it
+func timedRoundTrip(next http.RoundTripper, req *http.Request) (*http.Response, RoundTrip) {
+	start := time.Now()
+	res, err := next.RoundTrip(req)
+
+	rt := RoundTrip{
+		Endpoint: RequestEndpoint(req),
+		UUID:     RequestTaskUUID(req),
+		Method:   req.Method,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if res != nil {
+		rt.StatusCode = res.StatusCode
+	}
+
+	return res, rt
+}
+
+// LoggingMiddleware logs every request at Debug level and failed ones at Warn level,
+// context of request is passed to logger. Credentials are never logged.
+func LoggingMiddleware(l *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res, rt := timedRoundTrip(next, req)
+
+			attrs := []slog.Attr{
+				slog.String("endpoint", string(rt.Endpoint)),
+				slog.String("method", rt.Method),
+				slog.Int("status_code", rt.StatusCode),
+				slog.Duration("latency", rt.Duration),
+			}
+			if rt.UUID != "" {
+				attrs = append(attrs, slog.String("uuid", rt.UUID))
+			}
+
+			if rt.Err != nil || rt.StatusCode >= 400 {
+				if rt.Err != nil {
+					attrs = append(attrs, slog.String("error", rt.Err.Error()))
+				}
+				l.LogAttrs(req.Context(), slog.LevelWarn, "kandinsky round trip failed", attrs...)
+			} else {
+				l.LogAttrs(req.Context(), slog.LevelDebug, "kandinsky round trip", attrs...)
+			}
+
+			return res, rt.Err
+		})
+	}
+}
+
+// HeaderMiddleware sets headers h on every request, e.g. tracing or staging headers.
+// Auth headers are not overridden.
+func HeaderMiddleware(h http.Header) Middleware {
+	h = h.Clone()
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for name, vs := range h {
+				name = http.CanonicalHeaderKey(name)
+				if name == "X-Key" || name == "X-Secret" {
+					continue
+				}
+				req.Header[name] = vs
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
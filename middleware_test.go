@@ -0,0 +1,148 @@
+package kandinsky
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMiddleware common test
+func TestMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	var headers []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case modelsPath:
+			w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+		case runPath:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+		default:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+		}
+	}))
+	defer ts.Close()
+
+	// order of middleware calls and first status request fails
+	var order []string
+	var trips []RoundTrip
+	faulted := false
+	record := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	fault := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if RequestEndpoint(req) == EndpointStatus && !faulted {
+				faulted = true
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(strings.NewReader("")),
+					Header:     http.Header{},
+					Request:    req,
+				}, nil
+			}
+			return next.RoundTrip(req)
+		})
+	}
+
+	shared := &http.Client{}
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithHTTPClient(shared),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		WithMiddleware(
+			record("outer"),
+			TimingMiddleware(func(rt RoundTrip) { trips = append(trips, rt) }),
+			HeaderMiddleware(http.Header{"x-request-source": {"test"}, "X-Key": {"Key hijacked"}}),
+		),
+		WithMiddleware(record("inner"), fault),
+	)
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	if _, err := k.GetImage(params); err != nil {
+		t.Fatalf("get image error > %s", err)
+	}
+
+	if shared.Transport != nil {
+		t.Errorf("\nwant shared client not modified, got transport:\n\t\t%T\n", shared.Transport)
+	}
+
+	if len(order) != 8 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("\nwant outer then inner for 4 requests, got:\n\t\t%v\n", order)
+	}
+
+	want := []RoundTrip{
+		{Endpoint: EndpointModels, Method: http.MethodGet, StatusCode: 200},
+		{Endpoint: EndpointRun, Method: http.MethodPost, StatusCode: 200},
+		{Endpoint: EndpointStatus, UUID: "test-uuid", Method: http.MethodGet, StatusCode: 503},
+		{Endpoint: EndpointStatus, UUID: "test-uuid", Method: http.MethodGet, StatusCode: 200},
+	}
+	if len(trips) != len(want) {
+		t.Fatalf("\nwant round trips:\n\t\t%+v \n\tgot:\n\t\t%+v\n", want, trips)
+	}
+	for i, rt := range trips {
+		rt.Duration = 0
+		if rt != want[i] {
+			t.Errorf("\nwant round trip:\n\t\t%+v \n\tgot:\n\t\t%+v\n", want[i], rt)
+		}
+	}
+
+	// faulted request did not reach server
+	if len(headers) != 3 {
+		t.Fatalf("\nwant server requests:\n\t\t3 \n\tgot:\n\t\t%d\n", len(headers))
+	}
+	for _, h := range headers {
+		if h.Get("X-Request-Source") != "test" || h.Get("X-Key") != "Key key" {
+			t.Errorf("\nwant injected header and own key, got:\n\t\t%v\n", h)
+		}
+	}
+}
+
+// ctxHandler records context values of logged records
+type ctxHandler struct {
+	slog.Handler
+	values *[]any
+}
+
+func (h ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	*h.values = append(*h.values, ctx.Value(ctxKey{}))
+	return nil
+}
+
+type ctxKey struct{}
+
+// TestLoggingMiddleware checks that request context is passed to logger
+func TestLoggingMiddleware(t *testing.T) {
+	ts := (&fakeAPI{}).start(t)
+
+	var values []any
+	l := slog.New(ctxHandler{Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}), values: &values})
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithMiddleware(LoggingMiddleware(l)))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller")
+	if _, err := k.ListModelsContext(ctx); err != nil {
+		t.Fatalf("list models error > %s", err)
+	}
+
+	if len(values) != 1 || values[0] != "caller" {
+		t.Errorf("\nwant caller context logged once, got:\n\t\t%v\n", values)
+	}
+}
@@ -0,0 +1,194 @@
+package kandinsky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrModelNotFound        = errors.New("kandinsky model is not found")
+	ErrModelVersionMismatch = errors.New("kandinsky model version is not expected")
+)
+
+// Model types
+const (
+	// Generate image from text prompt
+	TEXT2IMAGE = "TEXT2IMAGE"
+)
+
+// DefaultModelTTL is how long models list is cached by default
+const DefaultModelTTL = time.Hour
+
+// ModelSelector reports whether model m fits.
+type ModelSelector func(m Model) bool
+
+// ByName selects model with name, e.g. "Kandinsky".
+func ByName(name string) ModelSelector {
+	return func(m Model) bool {
+		return m.Name == name
+	}
+}
+
+// ByType selects model with type, e.g. TEXT2IMAGE.
+func ByType(t string) ModelSelector {
+	return func(m Model) bool {
+		return m.Type == t
+	}
+}
+
+// MinVersion selects model with version v or newer.
+func MinVersion(v float32) ModelSelector {
+	return func(m Model) bool {
+		return m.Version >= v
+	}
+}
+
+// SelectModel returns first model from models fitting all selectors.
+// Without selectors first model is returned.
+func SelectModel(models []Model, selectors ...ModelSelector) (Model, error) {
+	for _, m := range models {
+		fits := true
+		for _, sel := range selectors {
+			if !sel(m) {
+				fits = false
+				break
+			}
+		}
+
+		if fits {
+			return m, nil
+		}
+	}
+
+	return Model{}, ErrModelNotFound
+}
+
+// ModelVersionError is returned by SetModel when selected model version differs
+// from version pinned by WithModelVersion.
+type ModelVersionError struct {
+	// Pinned version.
+	Want float32
+	// Model selected from Kandinsky API.
+	Got Model
+}
+
+func (e *ModelVersionError) Error() string {
+	return fmt.Sprintf("%s: want %v, got %s %v (id %d)", ErrModelVersionMismatch, e.Want, e.Got.Name, e.Got.Version, e.Got.ID)
+}
+
+// Is makes errors.Is(err, ErrModelVersionMismatch) true
+func (e *ModelVersionError) Is(target error) bool {
+	return target == ErrModelVersionMismatch
+}
+
+// WithModelSelector sets selectors used by SetModel to choose model from ListModels.
+func WithModelSelector(selectors ...ModelSelector) Option {
+	return func(k *Kand) {
+		k.selectors = selectors
+	}
+}
+
+// WithModelVersion pins expected model version, SetModel fails with
+// *ModelVersionError if Kandinsky API offers another one.
+func WithModelVersion(v float32) Option {
+	return func(k *Kand) {
+		k.version = v
+	}
+}
+
+// WithModelTTL sets how long models list is cached, 0 disables caching.
+// Model is selected again when the list expires, so 0 selects it for every generation.
+func WithModelTTL(d time.Duration) Option {
+	return func(k *Kand) {
+		k.modelTTL = d
+	}
+}
+
+// ListModels returns all models available in Kandinsky API.
+// List is cached for TTL set with WithModelTTL, parallel callers share one request.
+func (k *Kand) ListModels() ([]Model, error) {
+	return k.ListModelsContext(context.Background())
+}
+
+// ListModelsContext is like ListModels but uses ctx for the request.
+func (k *Kand) ListModelsContext(ctx context.Context) ([]Model, error) {
+	k.listMu.Lock()
+	defer k.listMu.Unlock()
+
+	if m, ok := k.cachedModels(); ok {
+		return m, nil
+	}
+
+	// do GET request with auth headers to Kandinsky API
+	ctx = withEndpoint(ctx, EndpointModels)
+	res, err := k.do(ctx, func() (*http.Request, error) {
+		return k.newRequest(ctx, http.MethodGet, k.authURL, nil)
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// unmarshal response
+	m := []Model{}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.modelTTL > 0 {
+		k.mu.Lock()
+		k.models = m
+		k.modelsAt = k.getClock().Now()
+		k.mu.Unlock()
+	}
+
+	return append([]Model(nil), m...), nil
+}
+
+// cachedModels returns copy of models list if it is not expired
+func (k *Kand) cachedModels() ([]Model, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.models == nil || k.getClock().Now().Sub(k.modelsAt) >= k.modelTTL {
+		return nil, false
+	}
+
+	return append([]Model(nil), k.models...), true
+}
+
+// CurrentModel returns model selected for generating images, zero Model if not set yet.
+func (k *Kand) CurrentModel() Model {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.Model
+}
+
+// ensureModel returns current model, model is set if not set yet or selected longer than TTL ago
+func (k *Kand) ensureModel(ctx context.Context) (Model, error) {
+	k.mu.RLock()
+	m, at := k.Model, k.selectedAt
+	k.mu.RUnlock()
+
+	if m.ID != 0 && (at.IsZero() || k.getClock().Now().Sub(at) < k.modelTTL) {
+		return m, nil
+	}
+
+	_, err := k.SetModelContext(ctx)
+	if err != nil {
+		return Model{}, err
+	}
+
+	return k.CurrentModel(), nil
+}
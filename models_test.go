@@ -0,0 +1,287 @@
+package kandinsky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSelectModel common test
+func TestSelectModel(t *testing.T) {
+	models := []Model{
+		{ID: 1, Name: "Kandinsky", Version: 2.2, Type: TEXT2IMAGE},
+		{ID: 4, Name: "Kandinsky", Version: 3.0, Type: TEXT2IMAGE},
+		{ID: 7, Name: "Upscaler", Version: 1.0, Type: "IMAGE2IMAGE"},
+	}
+
+	testCases := []struct {
+		desc      string
+		models    []Model
+		selectors []ModelSelector
+		id        int
+		want      error
+	}{
+		{
+			desc:   "First model without selectors",
+			models: models,
+			id:     1,
+			want:   nil,
+		},
+		{
+			desc:      "By name",
+			models:    models,
+			selectors: []ModelSelector{ByName("Upscaler")},
+			id:        7,
+			want:      nil,
+		},
+		{
+			desc:      "By type and min version",
+			models:    models,
+			selectors: []ModelSelector{ByType(TEXT2IMAGE), MinVersion(3)},
+			id:        4,
+			want:      nil,
+		},
+		{
+			desc:      "No model fits",
+			models:    models,
+			selectors: []ModelSelector{ByName("Upscaler"), ByType(TEXT2IMAGE)},
+			want:      ErrModelNotFound,
+		},
+		{
+			desc:   "Empty list",
+			models: []Model{},
+			want:   ErrModelNotFound,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m, err := SelectModel(tC.models, tC.selectors...)
+			if err != tC.want {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if m.ID != tC.id {
+				t.Errorf("\n%s:\n\twant id:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.id, m.ID)
+			}
+		})
+	}
+}
+
+// TestSetModelSelection checks model chosen by SetModel from models list
+func TestSetModelSelection(t *testing.T) {
+	testCases := []struct {
+		desc string
+		body string
+		opts []Option
+		id   int
+		want error
+	}{
+		{
+			desc: "Selected by type",
+			body: `[{"id":2,"name":"Upscaler","version":1.0,"type":"IMAGE2IMAGE"},{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`,
+			opts: []Option{WithModelSelector(ByType(TEXT2IMAGE))},
+			id:   4,
+			want: nil,
+		},
+		{
+			desc: "Pinned version matches",
+			body: `[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`,
+			opts: []Option{WithModelVersion(3)},
+			id:   4,
+			want: nil,
+		},
+		{
+			desc: "Pinned version swapped",
+			body: `[{"id":5,"name":"Kandinsky","version":3.1,"type":"TEXT2IMAGE"}]`,
+			opts: []Option{WithModelVersion(3)},
+			want: ErrModelVersionMismatch,
+		},
+		{
+			desc: "Empty models list",
+			body: `[]`,
+			want: ErrModelNotFound,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tC.body))
+			}))
+			defer ts.Close()
+
+			k, err := New("key", "secret", append(tC.opts, WithBaseURL(ts.URL))...)
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			id, err := k.SetModel()
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if id != tC.id {
+				t.Errorf("\n%s:\n\twant id:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.id, id)
+			}
+
+			var verErr *ModelVersionError
+			if errors.As(err, &verErr) && verErr.Got.ID != 5 {
+				t.Errorf("\n%s:\n\twant got model id:\n\t\t5 \n\tgot:\n\t\t%d\n", tC.desc, verErr.Got.ID)
+			}
+		})
+	}
+}
+
+// TestParallelGetImage checks that shared client requests models once for parallel generations
+func TestParallelGetImage(t *testing.T) {
+	var models, runs int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case modelsPath:
+			atomic.AddInt32(&models, 1)
+			w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+		case runPath:
+			atomic.AddInt32(&runs, 1)
+			w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+		default:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+		}
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := k.GetImage(NewParams("cat-" + strconv.Itoa(i)))
+			if err != nil {
+				t.Errorf("get image error > %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&models); got != 1 {
+		t.Errorf("\nwant models requests:\n\t\t1 \n\tgot:\n\t\t%d\n", got)
+	}
+
+	if got := atomic.LoadInt32(&runs); got != n {
+		t.Errorf("\nwant run requests:\n\t\t%d \n\tgot:\n\t\t%d\n", n, got)
+	}
+
+	if k.CurrentModel().ID != 4 {
+		t.Errorf("\nwant model id:\n\t\t4 \n\tgot:\n\t\t%d\n", k.CurrentModel().ID)
+	}
+}
+
+// TestModelTTL checks models list cache expiration
+func TestModelTTL(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+	}))
+	defer ts.Close()
+
+	testCases := []struct {
+		desc     string
+		ttl      time.Duration
+		requests int32
+	}{
+		{
+			desc:     "Cached within TTL",
+			ttl:      time.Hour,
+			requests: 1,
+		},
+		{
+			desc:     "Expired after TTL",
+			ttl:      time.Minute,
+			requests: 2,
+		},
+		{
+			desc:     "Caching disabled",
+			ttl:      0,
+			requests: 3,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			atomic.StoreInt32(&n, 0)
+			c := &fakeClock{now: time.Unix(0, 0)}
+
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithClock(c), WithModelTTL(tC.ttl))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			// second call at once, third one in 30 minutes
+			for i := 0; i < 3; i++ {
+				if i == 2 {
+					c.After(30 * time.Minute)
+				}
+				if _, err := k.ListModels(); err != nil {
+					t.Fatalf("\n%s: list models error > %s", tC.desc, err)
+				}
+			}
+
+			if got := atomic.LoadInt32(&n); got != tC.requests {
+				t.Errorf("\n%s:\n\twant requests:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.requests, got)
+			}
+		})
+	}
+}
+
+// TestModelReselect checks that model is selected again when models list expires
+func TestModelReselect(t *testing.T) {
+	var models int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case modelsPath:
+			if atomic.AddInt32(&models, 1) == 1 {
+				w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+				return
+			}
+			w.Write([]byte(`[{"id":5,"name":"Kandinsky","version":3.1,"type":"TEXT2IMAGE"}]`))
+		case runPath:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+		default:
+			w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := &fakeClock{now: time.Unix(0, 0)}
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithClock(c),
+		WithModelTTL(time.Hour), WithModelVersion(3))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	if _, err := k.GetImage(params); err != nil {
+		t.Fatalf("get image error > %s", err)
+	}
+
+	// model is swapped after models list expired
+	c.After(2 * time.Hour)
+	if _, err := k.GetImage(params); !errors.Is(err, ErrModelVersionMismatch) {
+		t.Errorf("\nwant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", ErrModelVersionMismatch, err)
+	}
+
+	if got := atomic.LoadInt32(&models); got != 2 {
+		t.Errorf("\nwant models requests:\n\t\t2 \n\tgot:\n\t\t%d\n", got)
+	}
+}
@@ -0,0 +1,68 @@
+package kandinsky
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Default Kandinsky API host and endpoint paths
+const (
+	// DefaultBaseURL is the fusionbrain API host
+	DefaultBaseURL = "https://api-key.fusionbrain.ai"
+
+	modelsPath = "/key/api/v1/models"
+	runPath    = "/key/api/v1/text2image/run"
+	statusPath = "/key/api/v1/text2image/status/"
+	availPath  = "/key/api/v1/text2image/availability"
+)
+
+// Option configures Kand instance created by New.
+type Option func(k *Kand)
+
+// WithHTTPClient sets http.Client used for all requests to Kandinsky API.
+// Allows to share connection pool or transport between clients.
+func WithHTTPClient(c *http.Client) Option {
+	return func(k *Kand) {
+		if c != nil {
+			k.client = c
+		}
+	}
+}
+
+// WithBaseURL sets Kandinsky API host, e.g. staging host or local fake server.
+// Endpoint paths are appended to base.
+func WithBaseURL(base string) Option {
+	return func(k *Kand) {
+		k.setBaseURL(base)
+	}
+}
+
+// WithTimeout sets timeout for every single request to Kandinsky API.
+// Client set by WithHTTPClient is copied, not modified.
+func WithTimeout(d time.Duration) Option {
+	return func(k *Kand) {
+		k.timeout = d
+	}
+}
+
+// WithUserAgent sets User-Agent header for all requests to Kandinsky API.
+func WithUserAgent(ua string) Option {
+	return func(k *Kand) {
+		k.userAgent = ua
+	}
+}
+
+// setBaseURL sets auth, generate and check URLs from base
+func (k *Kand) setBaseURL(base string) {
+	base = strings.TrimRight(base, "/")
+	if base == "" {
+		k.authURL, k.genURL, k.checkURL, k.availURL = "", "", "", ""
+		return
+	}
+
+	k.authURL = base + modelsPath
+	k.genURL = base + runPath
+	k.checkURL = base + statusPath
+	k.availURL = base + availPath
+}
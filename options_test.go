@@ -0,0 +1,105 @@
+package kandinsky
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestNewOptions common test
+func TestNewOptions(t *testing.T) {
+	shared := &http.Client{}
+
+	testCases := []struct {
+		desc     string
+		opts     []Option
+		authURL  string
+		checkURL string
+		timeout  time.Duration
+		err      error
+	}{
+		{
+			desc:     "Default base URL",
+			opts:     nil,
+			authURL:  DefaultBaseURL + modelsPath,
+			checkURL: DefaultBaseURL + statusPath,
+			err:      nil,
+		},
+		{
+			desc:     "Custom base URL with trailing slash",
+			opts:     []Option{WithBaseURL("http://localhost:8080/")},
+			authURL:  "http://localhost:8080" + modelsPath,
+			checkURL: "http://localhost:8080" + statusPath,
+			err:      nil,
+		},
+		{
+			desc:    "Empty base URL",
+			opts:    []Option{WithBaseURL("")},
+			authURL: "",
+			err:     ErrEmptyURL,
+		},
+		{
+			desc:     "Shared client with timeout",
+			opts:     []Option{WithHTTPClient(shared), WithTimeout(time.Second)},
+			authURL:  DefaultBaseURL + modelsPath,
+			checkURL: DefaultBaseURL + statusPath,
+			timeout:  time.Second,
+			err:      nil,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			i, err := New("key", "secret", tC.opts...)
+			if err != tC.err {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			k := i
+			if k.authURL != tC.authURL || k.checkURL != tC.checkURL {
+				t.Errorf("\n%s:\n\twant:\n\t\t\"%s\" \"%s\" \n\tgot:\n\t\t\"%s\" \"%s\"\n", tC.desc, tC.authURL, tC.checkURL, k.authURL, k.checkURL)
+			}
+			if k.client.Timeout != tC.timeout {
+				t.Errorf("\n%s:\n\twant timeout:\n\t\t%s \n\tgot:\n\t\t%s\n", tC.desc, tC.timeout, k.client.Timeout)
+			}
+		})
+	}
+
+	if shared.Timeout != 0 {
+		t.Errorf("shared client was modified, timeout == %s", shared.Timeout)
+	}
+}
+
+// TestWithUserAgent checks User-Agent header and base URL used by requests
+func TestWithUserAgent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != modelsPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if ua := r.Header.Get("User-Agent"); ua != "kandinsky-test/1.0" {
+			t.Errorf("\n\twant:\n\t\t\"kandinsky-test/1.0\" \n\tgot:\n\t\t\"%s\"\n", ua)
+		}
+
+		w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithUserAgent("kandinsky-test/1.0"))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	id, err := k.SetModel()
+	if err != nil {
+		t.Fatalf("set model error > %s", err)
+	}
+	if id != 4 {
+		t.Errorf("set model error, wrong model id == %d ", id)
+	}
+}
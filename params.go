@@ -0,0 +1,246 @@
+package kandinsky
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidParams is returned by Params.Validate, use errors.As with *ValidationError
+// to get every field problem.
+var ErrInvalidParams = errors.New("kandinsky params are invalid")
+
+// Params constraints of Kandinsky API
+const (
+	// Minimal width and height of image
+	MinSize = 128
+	// Maximal width and height of image
+	MaxSize = 1024
+	// Width and height must be multiple of SizeMultiple
+	SizeMultiple = 64
+	// Maximal prompt length in characters
+	MaxPromptLength = 1000
+	// Maximal negative prompt length in characters
+	MaxNegativePromptLength = 1000
+)
+
+// AspectRatio of image as width to height, e.g. {16, 9}.
+type AspectRatio struct {
+	Width  int
+	Height int
+}
+
+func (r AspectRatio) String() string {
+	return fmt.Sprintf("%d:%d", r.Width, r.Height)
+}
+
+// AspectRatios supported by Kandinsky API
+var AspectRatios = []AspectRatio{{1, 1}, {2, 3}, {3, 2}, {9, 16}, {16, 9}}
+
+// Styles supported by Kandinsky API
+var Styles = []string{KANDINSKY, UHD, ANIME, DEFAULT}
+
+// FieldError describes problem with single Params field.
+type FieldError struct {
+	// Json name of the field, e.g. "width".
+	Field string
+	// What is wrong with the field.
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every problem found by Params.Validate.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	s := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		s = append(s, fe.Error())
+	}
+
+	return ErrInvalidParams.Error() + ": " + strings.Join(s, "; ")
+}
+
+// Is makes errors.Is(err, ErrInvalidParams) true
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidParams
+}
+
+// add appends field problem
+func (e *ValidationError) add(field, format string, a ...any) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// Validate checks params against Kandinsky API constraints before sending.
+// Zero Width, Height, NumImages, Type and Style are valid as defaults are used for them.
+// Returns *ValidationError listing every problem or nil.
+func (p Params) Validate() error {
+	e := new(ValidationError)
+
+	validSize := true
+	for _, f := range []struct {
+		name string
+		v    int
+	}{{"width", p.Width}, {"height", p.Height}} {
+		if f.v == 0 {
+			continue
+		}
+		if f.v < MinSize || f.v > MaxSize {
+			e.add(f.name, "must be from %d to %d, got %d", MinSize, MaxSize, f.v)
+			validSize = false
+		} else if f.v%SizeMultiple != 0 {
+			e.add(f.name, "must be multiple of %d, got %d", SizeMultiple, f.v)
+			validSize = false
+		}
+	}
+
+	if validSize {
+		w, h := p.Width, p.Height
+		if w == 0 {
+			w = MinSize
+		}
+		if h == 0 {
+			h = MinSize
+		}
+		if !supportedRatio(w, h) {
+			e.add("width", "aspect ratio of %dx%d is not supported, use one of %v", w, h, AspectRatios)
+		}
+	}
+
+	if p.NumImages != 0 && p.NumImages != 1 {
+		e.add("num_images", "must be 1, got %d", p.NumImages)
+	}
+
+	if p.Type != "" && p.Type != "GENERATE" {
+		e.add("type", "must be GENERATE, got %q", p.Type)
+	}
+
+	if p.Style != "" && !knownStyle(p.Style) {
+		e.add("style", "must be one of %v, got %q", Styles, p.Style)
+	}
+
+	if p.GenerateParams.Query == "" {
+		e.add("query", "is empty")
+	} else if n := utf8.RuneCountInString(p.GenerateParams.Query); n > MaxPromptLength {
+		e.add("query", "must be at most %d characters, got %d", MaxPromptLength, n)
+	}
+
+	if n := utf8.RuneCountInString(p.NegativePrompt); n > MaxNegativePromptLength {
+		e.add("negativePromptUnclip", "must be at most %d characters, got %d", MaxNegativePromptLength, n)
+	}
+
+	if len(e.Errors) > 0 {
+		return e
+	}
+
+	return nil
+}
+
+// WithParamsValidation makes GetImageUUID validate params with Params.Validate
+// before sending request, so bad params do not cost a round trip.
+func WithParamsValidation() Option {
+	return func(k *Kand) {
+		k.validate = true
+	}
+}
+
+// supportedRatio reports whether w to h is one of AspectRatios
+func supportedRatio(w, h int) bool {
+	for _, r := range AspectRatios {
+		if w*r.Height == h*r.Width {
+			return true
+		}
+	}
+
+	return false
+}
+
+// knownStyle reports whether s is one of Styles
+func knownStyle(s string) bool {
+	for _, v := range Styles {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParamOption configures Params created by NewParams, named Param* to differ from client Options.
+type ParamOption func(p *Params)
+
+// NewParams creates Params for query with options applied in order,
+// see ParamSize, ParamAspectRatio, ParamStyle and ParamNegativePrompt.
+func NewParams(query string, opts ...ParamOption) Params {
+	p := Params{
+		NumImages:      1,
+		Type:           "GENERATE",
+		GenerateParams: GenerateParams{Query: query},
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// ParamSize sets width and height of image.
+func ParamSize(width, height int) ParamOption {
+	return func(p *Params) {
+		p.Width = width
+		p.Height = height
+	}
+}
+
+// ParamAspectRatio sets the largest width and height of ratio r fitting API constraints,
+// e.g. 1024x576 for 16:9. Size is not changed if r can not fit.
+func ParamAspectRatio(r AspectRatio) ParamOption {
+	return func(p *Params) {
+		if r.Width <= 0 || r.Height <= 0 {
+			return
+		}
+
+		big, small := r.Width, r.Height
+		if small > big {
+			big, small = small, big
+		}
+
+		for long := MaxSize; long >= MinSize; long -= SizeMultiple {
+			if long*small%big != 0 {
+				continue
+			}
+			short := long * small / big
+			if short < MinSize || short%SizeMultiple != 0 {
+				continue
+			}
+
+			if r.Width >= r.Height {
+				p.Width, p.Height = long, short
+			} else {
+				p.Width, p.Height = short, long
+			}
+			return
+		}
+	}
+}
+
+// ParamStyle sets style of image, e.g. KANDINSKY.
+func ParamStyle(style string) ParamOption {
+	return func(p *Params) {
+		p.Style = style
+	}
+}
+
+// ParamNegativePrompt sets what to avoid in the image.
+func ParamNegativePrompt(np string) ParamOption {
+	return func(p *Params) {
+		p.NegativePrompt = np
+	}
+}
@@ -0,0 +1,183 @@
+package kandinsky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestValidate common test
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		edit   func(p *Params)
+		fields []string
+	}{
+		{
+			desc:   "Valid params",
+			edit:   func(p *Params) {},
+			fields: nil,
+		},
+		{
+			desc: "Defaults are valid",
+			edit: func(p *Params) {
+				*p = Params{}
+				p.GenerateParams.Query = "black cat"
+			},
+			fields: nil,
+		},
+		{
+			desc: "Supported aspect ratio",
+			edit: func(p *Params) {
+				p.Width, p.Height = 576, 1024
+			},
+			fields: nil,
+		},
+		{
+			desc: "Size out of bounds",
+			edit: func(p *Params) {
+				p.Width, p.Height = 64, 2048
+			},
+			fields: []string{"width", "height"},
+		},
+		{
+			desc: "Size not multiple",
+			edit: func(p *Params) {
+				p.Width, p.Height = 1000, 1000
+			},
+			fields: []string{"width", "height"},
+		},
+		{
+			desc: "Unsupported aspect ratio",
+			edit: func(p *Params) {
+				p.Width, p.Height = 1024, 128
+			},
+			fields: []string{"width"},
+		},
+		{
+			desc: "Every problem at once",
+			edit: func(p *Params) {
+				p.NumImages = 2
+				p.Type = "WRONG"
+				p.Style = "WRONG"
+				p.GenerateParams.Query = ""
+				p.NegativePrompt = strings.Repeat("я", MaxNegativePromptLength+1)
+			},
+			fields: []string{"num_images", "type", "style", "query", "negativePromptUnclip"},
+		},
+		{
+			desc: "Prompt too long",
+			edit: func(p *Params) {
+				p.GenerateParams.Query = strings.Repeat("я", MaxPromptLength+1)
+			},
+			fields: []string{"query"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := params
+			tC.edit(&p)
+
+			err := p.Validate()
+			if tC.fields == nil {
+				if err != nil {
+					t.Fatalf("\n%s: unexpected error > %s", tC.desc, err)
+				}
+				return
+			}
+
+			var e *ValidationError
+			if !errors.As(err, &e) || !errors.Is(err, ErrInvalidParams) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, ErrInvalidParams, err)
+			}
+
+			fields := []string{}
+			for _, fe := range e.Errors {
+				fields = append(fields, fe.Field)
+			}
+			if !reflect.DeepEqual(fields, tC.fields) {
+				t.Errorf("\n%s:\n\twant fields:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, tC.fields, fields)
+			}
+		})
+	}
+}
+
+// TestWithParamsValidation checks that invalid params are not sent
+func TestWithParamsValidation(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithParamsValidation())
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+	k.Model = Model{ID: 4}
+
+	p := params
+	p.Style = "WRONG"
+
+	_, err = k.GetImageUUID(p)
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrInvalidParams, err)
+	}
+
+	if got := atomic.LoadInt32(&n); got != 0 {
+		t.Errorf("\nwant requests:\n\t\t0 \n\tgot:\n\t\t%d\n", got)
+	}
+}
+
+// TestNewParams common test
+func TestNewParams(t *testing.T) {
+	testCases := []struct {
+		desc string
+		opts []ParamOption
+		want Params
+	}{
+		{
+			desc: "Query only",
+			opts: nil,
+			want: Params{NumImages: 1, Type: "GENERATE", GenerateParams: GenerateParams{Query: "black cat"}},
+		},
+		{
+			desc: "Size, style and negative prompt",
+			opts: []ParamOption{ParamSize(1024, 1024), ParamStyle(UHD), ParamNegativePrompt("bright colors")},
+			want: Params{
+				Width: 1024, Height: 1024, NumImages: 1, Type: "GENERATE", Style: UHD,
+				NegativePrompt: "bright colors", GenerateParams: GenerateParams{Query: "black cat"},
+			},
+		},
+		{
+			desc: "Landscape aspect ratio",
+			opts: []ParamOption{ParamAspectRatio(AspectRatio{16, 9})},
+			want: Params{Width: 1024, Height: 576, NumImages: 1, Type: "GENERATE", GenerateParams: GenerateParams{Query: "black cat"}},
+		},
+		{
+			desc: "Portrait aspect ratio",
+			opts: []ParamOption{ParamAspectRatio(AspectRatio{2, 3})},
+			want: Params{Width: 640, Height: 960, NumImages: 1, Type: "GENERATE", GenerateParams: GenerateParams{Query: "black cat"}},
+		},
+		{
+			desc: "Aspect ratio not fitting",
+			opts: []ParamOption{ParamSize(512, 512), ParamAspectRatio(AspectRatio{100, 1})},
+			want: Params{Width: 512, Height: 512, NumImages: 1, Type: "GENERATE", GenerateParams: GenerateParams{Query: "black cat"}},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := NewParams("black cat", tC.opts...)
+			if !reflect.DeepEqual(p, tC.want) {
+				t.Errorf("\n%s:\n\twant:\n\t\t%+v \n\tgot:\n\t\t%+v\n", tC.desc, tC.want, p)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPollTimeout is returned when CheckImage gives up waiting for the task,
+// use errors.As with *PollTimeoutError to get task UUID.
+var ErrPollTimeout = errors.New("kandinsky polling timeout, task is not completed yet")
+
+// PollPolicy defines how CheckImage polls task status.
+type PollPolicy struct {
+	// Delay before the first status request.
+	InitialDelay time.Duration
+	// Delay between status requests.
+	Interval time.Duration
+	// Interval is multiplied by Multiplier after every request, values <= 1 mean fixed interval.
+	Multiplier float64
+	// Upper bound of interval growth, 0 means no bound.
+	MaxInterval time.Duration
+	// Maximum number of status requests, 0 means unlimited.
+	MaxAttempts int
+	// Overall polling deadline, 0 means unlimited.
+	Timeout time.Duration
+}
+
+// DefaultPollPolicy checks status right away, then every second growing up to 10 seconds.
+func DefaultPollPolicy() PollPolicy {
+	return PollPolicy{
+		InitialDelay: 0,
+		Interval:     time.Second,
+		Multiplier:   1.5,
+		MaxInterval:  time.Second * 10,
+	}
+}
+
+// withDefaults returns DefaultPollPolicy for zero p,
+// otherwise zero Interval is set to default one and other limits are kept
+func (p PollPolicy) withDefaults() PollPolicy {
+	if p == (PollPolicy{}) {
+		return DefaultPollPolicy()
+	}
+
+	if p.Interval <= 0 {
+		p.Interval = DefaultPollPolicy().Interval
+	}
+
+	return p
+}
+
+// next returns interval following d
+func (p PollPolicy) next(d time.Duration) time.Duration {
+	if p.Multiplier > 1 {
+		d = time.Duration(float64(d) * p.Multiplier)
+	}
+
+	if p.MaxInterval > 0 && d > p.MaxInterval {
+		d = p.MaxInterval
+	}
+
+	return d
+}
+
+// PollTimeoutError carries task UUID to resume polling later with CheckImage.
+type PollTimeoutError struct {
+	// UUID of the task still in progress.
+	UUID string
+	// Last status received from Kandinsky API.
+	Status string
+	// Number of status requests done.
+	Attempts int
+	// Time spent polling.
+	Elapsed time.Duration
+}
+
+func (e *PollTimeoutError) Error() string {
+	return fmt.Sprintf("%s: uuid %s, status %s after %d attempts in %s", ErrPollTimeout, e.UUID, e.Status, e.Attempts, e.Elapsed)
+}
+
+// Is makes errors.Is(err, ErrPollTimeout) true
+func (e *PollTimeoutError) Is(target error) bool {
+	return target == ErrPollTimeout
+}
+
+// PollError is returned when status request fails after retries or polling is stopped by ctx,
+// it carries task UUID to resume polling later with CheckImage or ResumeJob.
+type PollError struct {
+	// UUID of the task still in progress.
+	UUID string
+	// Last status received from Kandinsky API.
+	Status string
+	// Number of successful status requests.
+	Attempts int
+	// Error of the status request or ctx.
+	Err error
+}
+
+func (e *PollError) Error() string {
+	return fmt.Sprintf("kandinsky polling error: uuid %s, status %s after %d attempts: %s", e.UUID, e.Status, e.Attempts, e.Err)
+}
+
+func (e *PollError) Unwrap() error {
+	return e.Err
+}
+
+// Clock is source of time for polling, replace it in tests with WithClock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock uses time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithPollPolicy sets polling policy for CheckImage, zero Interval means one second.
+func WithPollPolicy(p PollPolicy) Option {
+	return func(k *Kand) {
+		k.poll = p
+	}
+}
+
+// WithClock sets clock used for polling delays and deadlines.
+func WithClock(c Clock) Option {
+	return func(k *Kand) {
+		if c != nil {
+			k.clock = c
+		}
+	}
+}
+
+// getClock returns clock or real one if Kand was created without New
+func (k *Kand) getClock() Clock {
+	if k.clock == nil {
+		return realClock{}
+	}
+
+	return k.clock
+}
+
+// sleep waits d or until ctx is done
+func sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,145 @@
+package kandinsky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock advances immediately on After and records requested delays
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.delays = append(c.delays, d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// TestPollPolicy common test
+func TestPollPolicy(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		policy    PollPolicy
+		doneAfter int32
+		delays    []time.Duration
+		attempts  int32
+		want      error
+	}{
+		{
+			desc:      "Backoff with cap until DONE",
+			policy:    PollPolicy{InitialDelay: time.Second, Interval: time.Second, Multiplier: 2, MaxInterval: 3 * time.Second},
+			doneAfter: 5,
+			delays:    []time.Duration{time.Second, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+			attempts:  5,
+			want:      nil,
+		},
+		{
+			desc:      "Max attempts",
+			policy:    PollPolicy{Interval: time.Second, MaxAttempts: 3},
+			doneAfter: 0,
+			delays:    []time.Duration{time.Second, time.Second},
+			attempts:  3,
+			want:      ErrPollTimeout,
+		},
+		{
+			desc:      "Overall deadline",
+			policy:    PollPolicy{Interval: 4 * time.Second, Timeout: 10 * time.Second},
+			doneAfter: 0,
+			delays:    []time.Duration{4 * time.Second, 4 * time.Second, 2 * time.Second},
+			attempts:  4,
+			want:      ErrPollTimeout,
+		},
+		{
+			desc:      "Default interval keeps limits",
+			policy:    PollPolicy{MaxAttempts: 2},
+			doneAfter: 0,
+			delays:    []time.Duration{time.Second},
+			attempts:  2,
+			want:      ErrPollTimeout,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f := &fakeAPI{status: doneAfter(tC.doneAfter)}
+			ts := f.start(t)
+			c := &fakeClock{now: time.Unix(0, 0)}
+
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithPollPolicy(tC.policy), WithClock(c))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			i, err := k.CheckImage(&UUID{ID: "test-uuid"})
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if err == nil && i.Status != "DONE" {
+				t.Errorf("\n%s: error status image == %s", tC.desc, i.Status)
+			}
+
+			var pte *PollTimeoutError
+			if errors.As(err, &pte) && pte.UUID != "test-uuid" {
+				t.Errorf("\n%s: timeout error UUID == %q", tC.desc, pte.UUID)
+			}
+
+			if !reflect.DeepEqual(c.delays, tC.delays) {
+				t.Errorf("\n%s:\n\twant delays:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, tC.delays, c.delays)
+			}
+
+			if got := atomic.LoadInt32(&f.statuses); got != tC.attempts {
+				t.Errorf("\n%s:\n\twant attempts:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.attempts, got)
+			}
+		})
+	}
+}
+
+// TestPollError checks that failed status request error carries task UUID
+func TestPollError(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			w.Write([]byte(`{"uuid":"test-uuid","status":"PROCESSING"}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithClock(&fakeClock{now: time.Unix(0, 0)}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	_, err = k.CheckImage(&UUID{ID: "test-uuid"})
+	if !errors.Is(err, ErrInternalServerError) {
+		t.Fatalf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrInternalServerError, err)
+	}
+
+	var pe *PollError
+	if !errors.As(err, &pe) || pe.UUID != "test-uuid" || pe.Status != "PROCESSING" || pe.Attempts != 1 {
+		t.Errorf("\nwant poll error with uuid, status and attempts, got:\n\t\t%#v\n", pe)
+	}
+}
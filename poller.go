@@ -0,0 +1,254 @@
+package kandinsky
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultPollerWorkers is number of parallel status requests of shared poller by default
+const DefaultPollerWorkers = 4
+
+// SharedPollerOptions configures shared poller set with WithSharedPoller.
+type SharedPollerOptions struct {
+	// Number of status requests allowed per Interval for all tasks, 0 means unlimited.
+	Requests int
+	// Interval for Requests, e.g. time.Second.
+	Interval time.Duration
+	// Number of parallel status requests, values < 1 mean DefaultPollerWorkers.
+	Workers int
+}
+
+// WithSharedPoller makes all tasks of client polled by one shared poller instead of
+// separate loops. Poller tracks outstanding task UUIDs, does status checks in order of
+// their due time within global budget and dispatches results to waiting callers.
+// Callers waiting for the same UUID share status requests.
+func WithSharedPoller(o SharedPollerOptions) Option {
+	return func(k *Kand) {
+		k.sharedPoll = &o
+	}
+}
+
+// pollResult is result of single status request
+type pollResult struct {
+	image *Image
+	err   error
+}
+
+// pollWaiter is caller waiting for status of task
+type pollWaiter struct {
+	// Context of caller, carries its span and logging attributes.
+	ctx   context.Context
+	reply chan pollResult
+}
+
+// pollEntry is outstanding status check of task
+type pollEntry struct {
+	// UUID of the task.
+	id string
+	// When status must be checked.
+	due time.Time
+	// Order of adding, earlier entry goes first on equal due.
+	seq uint64
+	// Callers waiting for result.
+	waiters []pollWaiter
+	// Index in queue.
+	index int
+}
+
+// pollQueue is heap of entries ordered by due time
+type pollQueue []*pollEntry
+
+func (q pollQueue) Len() int { return len(q) }
+
+func (q pollQueue) Less(i, j int) bool {
+	if q[i].due.Equal(q[j].due) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].due.Before(q[j].due)
+}
+
+func (q pollQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *pollQueue) Push(x any) {
+	e := x.(*pollEntry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *pollQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	e.index = -1
+	return e
+}
+
+// poller schedules status checks of all tasks of client
+type poller struct {
+	k       *Kand
+	clock   Clock
+	limiter *limiter
+	// Limits parallel status requests.
+	workers chan struct{}
+	// Wakes scheduler when earlier entry is added.
+	wake chan struct{}
+
+	// Guards fields below.
+	mu sync.Mutex
+	// Entries ordered by due time.
+	queue pollQueue
+	// Entries by task UUID.
+	entries map[string]*pollEntry
+	// Counter for entries order.
+	seq uint64
+	// Whether scheduler goroutine is running.
+	running bool
+}
+
+// newPoller returns shared poller for k with options o
+func newPoller(k *Kand, o SharedPollerOptions) *poller {
+	n := o.Workers
+	if n < 1 {
+		n = DefaultPollerWorkers
+	}
+
+	c := k.getClock()
+
+	return &poller{
+		k:       k,
+		clock:   c,
+		limiter: newLimiter(RateLimit{Requests: o.Requests, Interval: o.Interval}, c),
+		workers: make(chan struct{}, n),
+		wake:    make(chan struct{}, 1),
+		entries: make(map[string]*pollEntry),
+	}
+}
+
+// check waits until status of task id is checked not earlier than delay from now
+func (p *poller) check(ctx context.Context, id string, delay time.Duration) (*Image, error) {
+	reply := make(chan pollResult, 1)
+	due := p.clock.Now().Add(delay)
+
+	p.mu.Lock()
+	e, ok := p.entries[id]
+	if ok {
+		// share request with other callers, but not later than needed
+		if due.Before(e.due) {
+			e.due = due
+			heap.Fix(&p.queue, e.index)
+		}
+		e.waiters = append(e.waiters, pollWaiter{ctx: ctx, reply: reply})
+	} else {
+		p.seq++
+		e = &pollEntry{id: id, due: due, seq: p.seq, waiters: []pollWaiter{{ctx: ctx, reply: reply}}}
+		p.entries[id] = e
+		heap.Push(&p.queue, e)
+	}
+
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-ctx.Done():
+		p.leave(id, reply)
+		return nil, ctx.Err()
+	case r := <-reply:
+		return r.image, r.err
+	}
+}
+
+// leave removes waiter of task id, entry without waiters is removed
+func (p *poller) leave(id string, reply chan pollResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.entries[id]
+	if !ok {
+		return
+	}
+
+	for i, w := range e.waiters {
+		if w.reply == reply {
+			e.waiters = append(e.waiters[:i], e.waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(e.waiters) == 0 {
+		delete(p.entries, id)
+		heap.Remove(&p.queue, e.index)
+	}
+}
+
+// run does due status checks until queue is empty
+func (p *poller) run() {
+	for {
+		p.mu.Lock()
+		if p.queue.Len() == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		wait := p.queue[0].due.Sub(p.clock.Now())
+		p.mu.Unlock()
+
+		if wait > 0 {
+			select {
+			case <-p.clock.After(wait):
+			case <-p.wake:
+			}
+			continue
+		}
+
+		// budget is taken before entry, so entry due while waiting goes first
+		_ = p.limiter.wait(context.Background(), false)
+		p.workers <- struct{}{}
+
+		p.mu.Lock()
+		if p.queue.Len() == 0 || p.queue[0].due.After(p.clock.Now()) {
+			p.mu.Unlock()
+			<-p.workers
+			continue
+		}
+		e := heap.Pop(&p.queue).(*pollEntry)
+		delete(p.entries, e.id)
+		// request belongs to the earliest waiter, but is not canceled with it
+		ctx := context.WithoutCancel(e.waiters[0].ctx)
+		p.mu.Unlock()
+
+		go func() {
+			defer func() { <-p.workers }()
+
+			image, err := p.k.checkStatus(ctx, e.id)
+
+			p.mu.Lock()
+			waiters := e.waiters
+			p.mu.Unlock()
+
+			for _, w := range waiters {
+				r := pollResult{err: err}
+				if image != nil {
+					// every caller gets own copy
+					i := *image
+					r.image = &i
+				}
+				w.reply <- r
+			}
+		}()
+	}
+}
@@ -0,0 +1,97 @@
+package kandinsky
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSharedPoller common test
+func TestSharedPoller(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		opts        SharedPollerOptions
+		ids         int
+		callers     int
+		doneAfter   int32
+		maxRequests int32
+		maxInFlight int32
+		minElapsed  time.Duration
+	}{
+		{
+			desc:        "Many tasks with few workers",
+			opts:        SharedPollerOptions{Workers: 2},
+			ids:         30,
+			callers:     1,
+			doneAfter:   2,
+			maxRequests: 60,
+			maxInFlight: 2,
+		},
+		{
+			desc:        "Callers of same task share requests",
+			opts:        SharedPollerOptions{},
+			ids:         1,
+			callers:     10,
+			doneAfter:   1,
+			maxRequests: 1,
+			maxInFlight: 1,
+		},
+		{
+			desc:        "Global request budget",
+			opts:        SharedPollerOptions{Requests: 5, Interval: 100 * time.Millisecond},
+			ids:         10,
+			callers:     1,
+			doneAfter:   1,
+			maxRequests: 10,
+			maxInFlight: DefaultPollerWorkers,
+			minElapsed:  80 * time.Millisecond,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f := &fakeAPI{status: doneAfter(tC.doneAfter), delay: 2 * time.Millisecond}
+			ts := f.start(t)
+
+			policy := PollPolicy{InitialDelay: 20 * time.Millisecond, Interval: time.Millisecond}
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithPollPolicy(policy), WithSharedPoller(tC.opts))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			for i := 0; i < tC.ids; i++ {
+				for j := 0; j < tC.callers; j++ {
+					wg.Add(1)
+					go func(id string) {
+						defer wg.Done()
+						image, err := k.CheckImage(&UUID{ID: id})
+						if err != nil {
+							t.Errorf("check image error > %s", err)
+							return
+						}
+						if image.UUID != id {
+							t.Errorf("\nwant uuid:\n\t\t\"%s\" \n\tgot:\n\t\t\"%s\"\n", id, image.UUID)
+						}
+					}("uuid-" + strconv.Itoa(i))
+				}
+			}
+			wg.Wait()
+
+			if got := atomic.LoadInt32(&f.statuses); got > tC.maxRequests {
+				t.Errorf("\n%s:\n\twant requests at most:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.maxRequests, got)
+			}
+
+			if got := atomic.LoadInt32(&f.maxInFlight); got > tC.maxInFlight {
+				t.Errorf("\n%s:\n\twant parallel requests at most:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.maxInFlight, got)
+			}
+
+			if elapsed := time.Since(start); elapsed < tC.minElapsed {
+				t.Errorf("\n%s:\n\twant elapsed at least:\n\t\t%s \n\tgot:\n\t\t%s\n", tC.desc, tC.minElapsed, elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when client rate limit is reached and RateLimit.NoWait is set.
+var ErrRateLimited = errors.New("kandinsky client rate limit is reached")
+
+// RateLimit defines client side limits of Kandinsky API key.
+type RateLimit struct {
+	// Number of requests allowed per Interval, 0 means unlimited.
+	// Every request counts including retries and status polls.
+	Requests int
+	// Interval for Requests, e.g. time.Minute.
+	Interval time.Duration
+	// Maximum number of generations in progress started by GetImage, Submit
+	// and GenerateBatch, 0 means unlimited.
+	MaxInFlight int
+	// Fail with ErrRateLimited instead of waiting for generation slot or run request.
+	// Requests for tasks already submitted, e.g. status polls, always wait.
+	NoWait bool
+}
+
+// WithRateLimit sets client side limits for requests and generations in progress.
+func WithRateLimit(l RateLimit) Option {
+	return func(k *Kand) {
+		k.rate = l
+	}
+}
+
+// limiter is token bucket for requests and semaphore for generations
+type limiter struct {
+	rate  RateLimit
+	clock Clock
+	// Generations in progress, nil means unlimited.
+	slots chan struct{}
+
+	// Guards tokens and last.
+	mu sync.Mutex
+	// Requests available now.
+	tokens float64
+	// When tokens were refilled.
+	last time.Time
+}
+
+// newLimiter returns limiter for l, nil if l has no limits
+func newLimiter(l RateLimit, c Clock) *limiter {
+	if (l.Requests <= 0 || l.Interval <= 0) && l.MaxInFlight <= 0 {
+		return nil
+	}
+
+	lim := &limiter{
+		rate:   l,
+		clock:  c,
+		tokens: float64(l.Requests),
+		last:   c.Now(),
+	}
+	if l.MaxInFlight > 0 {
+		lim.slots = make(chan struct{}, l.MaxInFlight)
+	}
+
+	return lim
+}
+
+// wait blocks until request is allowed or ctx is done,
+// admission request fails with ErrRateLimited at once if NoWait is set
+func (l *limiter) wait(ctx context.Context, admission bool) error {
+	if l == nil || l.rate.Requests <= 0 || l.rate.Interval <= 0 {
+		return nil
+	}
+
+	per := l.rate.Interval / time.Duration(l.rate.Requests)
+
+	for {
+		l.mu.Lock()
+		now := l.clock.Now()
+		l.tokens += float64(now.Sub(l.last)) / float64(per)
+		if max := float64(l.rate.Requests); l.tokens > max {
+			l.tokens = max
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) * float64(per))
+		l.mu.Unlock()
+
+		if l.rate.NoWait && admission {
+			return ErrRateLimited
+		}
+
+		err := sleep(ctx, l.clock, delay)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// acquire takes generation slot, release must be called when generation is finished
+func (l *limiter) acquire(ctx context.Context) error {
+	if l == nil || l.slots == nil {
+		return nil
+	}
+
+	if l.rate.NoWait {
+		select {
+		case l.slots <- struct{}{}:
+			return nil
+		default:
+			return ErrRateLimited
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case l.slots <- struct{}{}:
+		return nil
+	}
+}
+
+// release frees generation slot taken by acquire
+func (l *limiter) release() {
+	if l == nil || l.slots == nil {
+		return
+	}
+
+	<-l.slots
+}
@@ -0,0 +1,129 @@
+package kandinsky
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestLimiterWait common test
+func TestLimiterWait(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		rate   RateLimit
+		polls  bool
+		calls  int
+		delays []time.Duration
+		want   error
+	}{
+		{
+			desc:   "Burst then steady rate",
+			rate:   RateLimit{Requests: 2, Interval: time.Second},
+			calls:  5,
+			delays: []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+			want:   nil,
+		},
+		{
+			desc:  "No wait",
+			rate:  RateLimit{Requests: 2, Interval: time.Second, NoWait: true},
+			calls: 3,
+			want:  ErrRateLimited,
+		},
+		{
+			desc:   "No wait does not apply to polls",
+			rate:   RateLimit{Requests: 2, Interval: time.Second, NoWait: true},
+			polls:  true,
+			calls:  3,
+			delays: []time.Duration{500 * time.Millisecond},
+			want:   nil,
+		},
+		{
+			desc:  "Unlimited",
+			rate:  RateLimit{},
+			calls: 100,
+			want:  nil,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := &fakeClock{now: time.Unix(0, 0)}
+			l := newLimiter(tC.rate, c)
+
+			var err error
+			for i := 0; i < tC.calls && err == nil; i++ {
+				err = l.wait(context.Background(), !tC.polls)
+			}
+
+			if err != tC.want {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if !reflect.DeepEqual(c.delays, tC.delays) {
+				t.Errorf("\n%s:\n\twant delays:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, tC.delays, c.delays)
+			}
+		})
+	}
+}
+
+// TestLimiterAcquire common test
+func TestLimiterAcquire(t *testing.T) {
+	l := newLimiter(RateLimit{MaxInFlight: 1, NoWait: true}, realClock{})
+
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire error > %s", err)
+	}
+
+	if err := l.acquire(context.Background()); err != ErrRateLimited {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", ErrRateLimited, err)
+	}
+
+	l.release()
+
+	if err := l.acquire(context.Background()); err != nil {
+		t.Errorf("acquire after release error > %s", err)
+	}
+}
+
+// TestMaxInFlight checks that generations in progress are capped
+func TestMaxInFlight(t *testing.T) {
+	var active, maxActive int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case modelsPath:
+			w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+		case runPath:
+			storeMax(&maxActive, atomic.AddInt32(&active, 1))
+			w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+		default:
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+		}
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithRateLimit(RateLimit{MaxInFlight: 2}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	ps := make([]Params, 10)
+	for i := range ps {
+		ps[i] = NewParams("cat-" + strconv.Itoa(i))
+	}
+
+	_, err = CollectBatch(k.GenerateBatch(context.Background(), ps, BatchOptions{Concurrency: 10}))
+	if err != nil {
+		t.Fatalf("batch error > %s", err)
+	}
+
+	if got := atomic.LoadInt32(&maxActive); got > 2 {
+		t.Errorf("\nwant max generations in progress:\n\t\t2 \n\tgot:\n\t\t%d\n", got)
+	}
+}
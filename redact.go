@@ -0,0 +1,160 @@
+package kandinsky
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RedactedMask replaces secrets in errors and debug output.
+const RedactedMask = "***"
+
+// Headers which values are masked in errors and debug output
+var sensitiveHeaders = []string{"X-Key", "X-Secret", "Authorization"}
+
+// Redact returns s with every occurrence of non-empty secrets replaced by RedactedMask.
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, RedactedMask)
+		}
+	}
+
+	return s
+}
+
+// requestSecrets returns key and secret sent in request headers
+func requestSecrets(req *http.Request) []string {
+	if req == nil {
+		return nil
+	}
+
+	var secrets []string
+	for _, name := range sensitiveHeaders {
+		for _, v := range req.Header.Values(name) {
+			// value is "Key <key>", "Secret <secret>" or "Bearer <token>"
+			if _, secret, ok := strings.Cut(v, " "); ok {
+				v = secret
+			}
+			secrets = append(secrets, v)
+		}
+	}
+
+	return secrets
+}
+
+// redactHeader returns copy of h with sensitive values masked, scheme word is kept
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range sensitiveHeaders {
+		vs := h.Values(name)
+		for i, v := range vs {
+			if scheme, _, ok := strings.Cut(v, " "); ok {
+				vs[i] = scheme + " " + RedactedMask
+			} else {
+				vs[i] = RedactedMask
+			}
+		}
+	}
+
+	return h
+}
+
+// redactRequest returns copy of req without credentials in headers
+func redactRequest(req *http.Request) *http.Request {
+	if req == nil {
+		return nil
+	}
+
+	r := req.Clone(req.Context())
+	r.Header = redactHeader(req.Header)
+
+	return r
+}
+
+// Curl returns curl command reproducing the failed request, credentials are masked.
+func (e *APIError) Curl() string {
+	if e.Request == nil {
+		return ""
+	}
+
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "curl -X %s", e.Request.Method)
+
+	names := make([]string, 0, len(e.Request.Header))
+	for name := range e.Request.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range e.Request.Header[name] {
+			fmt.Fprintf(b, " -H %s", shellQuote(name+": "+v))
+		}
+	}
+
+	if e.Request.GetBody != nil {
+		if body, err := e.Request.GetBody(); err == nil {
+			data, err := io.ReadAll(body)
+			body.Close()
+			if err == nil && len(data) > 0 {
+				fmt.Fprintf(b, " --data-binary %s", shellQuote(string(data)))
+			}
+		}
+	}
+
+	fmt.Fprintf(b, " %s", shellQuote(e.Endpoint))
+
+	return b.String()
+}
+
+// shellQuote quotes s for POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// String masks key and secret.
+func (c Credentials) String() string {
+	return fmt.Sprintf("{Key:%s Secret:%s}", mask(c.Key), mask(c.Secret))
+}
+
+// GoString masks key and secret.
+func (c Credentials) GoString() string {
+	return fmt.Sprintf("kandinsky.Credentials{Key:%q, Secret:%q}", mask(c.Key), mask(c.Secret))
+}
+
+// String masks key and secret.
+func (c Config) String() string {
+	type config Config
+	c.Key, c.Secret = mask(c.Key), mask(c.Secret)
+
+	return fmt.Sprintf("%+v", config(c))
+}
+
+// GoString masks key and secret.
+func (c Config) GoString() string {
+	type config Config
+	c.Key, c.Secret = mask(c.Key), mask(c.Secret)
+
+	return fmt.Sprintf("kandinsky.Config%+v", config(c))
+}
+
+// String describes client without credentials.
+func (k *Kand) String() string {
+	return fmt.Sprintf("kandinsky.Kand{Endpoint:%s Model:%+v}", k.genURL, k.CurrentModel())
+}
+
+// GoString describes client without credentials.
+func (k *Kand) GoString() string {
+	return k.String()
+}
+
+// mask returns RedactedMask for non-empty s
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return RedactedMask
+}
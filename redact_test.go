@@ -0,0 +1,85 @@
+package kandinsky
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRedact common test
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		s       string
+		secrets []string
+		want    string
+	}{
+		{
+			desc:    "Key and secret",
+			s:       "invalid key my-key and secret my-secret",
+			secrets: []string{"my-key", "my-secret"},
+			want:    "invalid key *** and secret ***",
+		},
+		{
+			desc:    "Empty secret is ignored",
+			s:       "nothing to hide",
+			secrets: []string{""},
+			want:    "nothing to hide",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := Redact(tC.s, tC.secrets...); got != tC.want {
+				t.Errorf("\n%s:\n\twant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%s\"\n", tC.desc, tC.want, got)
+			}
+		})
+	}
+}
+
+// TestAPIErrorRedacted checks that credentials do not leak from APIError
+func TestAPIErrorRedacted(t *testing.T) {
+	const key, secret = "leaky-key", "leaky-secret"
+
+	// server echoes credentials in error message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, `{"status":401,"error":"Unauthorized","message":"bad %s / %s"}`, r.Header.Get("X-Key"), r.Header.Get("X-Secret"))
+	}))
+	defer ts.Close()
+
+	k, err := New(key, secret, WithBaseURL(ts.URL), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	_, err = k.SetModel()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("\nwant *APIError, got:\n\t\t%v\n", err)
+	}
+
+	curl := apiErr.Curl()
+	if !strings.Contains(curl, "'X-Key: Key ***'") || !strings.Contains(curl, ts.URL+modelsPath) {
+		t.Errorf("\nwant curl with masked key, got:\n\t\t%s\n", curl)
+	}
+
+	dumps := []string{
+		err.Error(),
+		string(apiErr.Body),
+		apiErr.Response.Message,
+		curl,
+		fmt.Sprintf("%v", apiErr.Request.Header),
+		fmt.Sprintf("%v %+v %#v", k, k, k),
+		fmt.Sprintf("%v %+v %#v", Credentials{key, secret}, Credentials{key, secret}, Credentials{key, secret}),
+		fmt.Sprintf("%v %+v %#v", Config{Key: key, Secret: secret}, Config{Key: key, Secret: secret}, Config{Key: key, Secret: secret}),
+	}
+	for _, s := range dumps {
+		if strings.Contains(s, key) || strings.Contains(s, secret) {
+			t.Errorf("\ncredentials leaked:\n\t\t%s\n", s)
+		}
+	}
+}
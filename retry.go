@@ -0,0 +1,195 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy defines how failed requests to Kandinsky API are retried.
+type RetryPolicy struct {
+	// Maximum number of attempts including the first one, values <= 1 disable retries.
+	MaxAttempts int
+	// Delay before the first retry.
+	InitialBackoff time.Duration
+	// Upper bound of backoff growth, 0 means no bound.
+	MaxBackoff time.Duration
+	// Backoff is multiplied by Multiplier after every retry, values <= 1 mean fixed backoff.
+	Multiplier float64
+	// Random part of backoff from 0 to 1, e.g. 0.2 means backoff ±20%.
+	Jitter float64
+}
+
+// MaxRetryAfter is the longest delay taken from Retry-After header, unless RetryPolicy.MaxBackoff is longer.
+const MaxRetryAfter = time.Minute
+
+// DefaultRetryPolicy does up to 3 attempts with backoff from 500ms to 10s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond * 500,
+		MaxBackoff:     time.Second * 10,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetryPolicy sets retry policy for requests to Kandinsky API.
+// Use RetryPolicy{} to disable retries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(k *Kand) {
+		k.retry = p
+	}
+}
+
+// backoff returns delay before retry number n starting from 1
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < n && p.Multiplier > 1; i++ {
+		d *= p.Multiplier
+		if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+			break
+		}
+	}
+
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// IsRetryable reports whether request failed with err may succeed if repeated:
+// network errors, 408, 429 and 5xx statuses except 501.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		case http.StatusNotImplemented:
+			return false
+		}
+		return apiErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isNotSubmitted reports whether failed request surely did not reach Kandinsky API
+// or was rejected before processing, so repeating it does not create one more task.
+func isNotSubmitted(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial"
+	}
+
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// do sends request created by newReq and retries it following retry policy.
+// Not idempotent requests are retried only if they surely were not submitted.
+// Rejected credentials are refreshed and request is repeated once.
+// Response has 2xx status, caller must close its body.
+func (k *Kand) do(ctx context.Context, newReq func() (*http.Request, error), idempotent bool) (*http.Response, error) {
+	p := k.retry
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		// only submission may be rejected, task already paid for must be polled
+		err := k.limiter.wait(ctx, endpointFrom(ctx) == EndpointRun)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		start := k.getClock().Now()
+		res, err := k.httpClient().Do(req)
+		if err == nil {
+			err = checkResponse(res)
+		}
+		k.logRequest(ctx, req, res, err, attempt, k.getClock().Now().Sub(start))
+		if err == nil {
+			return res, nil
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		// rejected request is not processed, so it is safe to repeat any
+		if !refreshed && errors.Is(err, ErrUnauthorized) && k.refreshCredentials(ctx) {
+			k.log(ctx, slog.LevelInfo, "kandinsky credentials refreshed", slog.String("endpoint", req.URL.Redacted()))
+			refreshed = true
+			attempt--
+			continue
+		}
+
+		if attempt >= p.MaxAttempts || !IsRetryable(err) || (!idempotent && !isNotSubmitted(err)) {
+			// error of status request is logged once by polling task
+			if endpointFrom(ctx) != EndpointStatus {
+				k.log(ctx, slog.LevelError, "kandinsky request error", slog.String("endpoint", req.URL.Redacted()), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+			}
+			return nil, err
+		}
+
+		delay := p.backoff(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = min(apiErr.RetryAfter, max(MaxRetryAfter, p.MaxBackoff))
+		}
+
+		k.log(ctx, slog.LevelWarn, "kandinsky retrying request", slog.String("endpoint", req.URL.Redacted()), slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.String("error", err.Error()))
+
+		// ctx error tells caller why it stopped, last error is logged above
+		err = sleep(ctx, k.getClock(), delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseRetryAfter parses Retry-After header in seconds or HTTP date
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if s, err := strconv.Atoi(v); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,189 @@
+package kandinsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetry common test
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 4}
+
+	testCases := []struct {
+		desc       string
+		path       string
+		codes      []int
+		retryAfter string
+		attempts   int32
+		delays     []time.Duration
+		want       error
+	}{
+		{
+			desc:     "Models retried after server errors",
+			path:     modelsPath,
+			codes:    []int{500, 502, 200},
+			attempts: 3,
+			delays:   []time.Duration{time.Second, 3 * time.Second},
+			want:     nil,
+		},
+		{
+			desc:     "Models attempts exhausted",
+			path:     modelsPath,
+			codes:    []int{503, 503, 503, 200},
+			attempts: 3,
+			delays:   []time.Duration{time.Second, 3 * time.Second},
+			want:     ErrServiceUnavailable,
+		},
+		{
+			desc:       "Retry-After header honored",
+			path:       statusPath,
+			codes:      []int{429, 200},
+			retryAfter: "7",
+			attempts:   2,
+			delays:     []time.Duration{7 * time.Second},
+			want:       nil,
+		},
+		{
+			desc:       "Retry-After header capped",
+			path:       statusPath,
+			codes:      []int{503, 200},
+			retryAfter: "36000",
+			attempts:   2,
+			delays:     []time.Duration{MaxRetryAfter},
+			want:       nil,
+		},
+		{
+			desc:     "Bad request not retried",
+			path:     statusPath,
+			codes:    []int{400, 200},
+			attempts: 1,
+			want:     ErrBadRequest,
+		},
+		{
+			desc:     "Run not retried after server error",
+			path:     runPath,
+			codes:    []int{500, 200},
+			attempts: 1,
+			want:     ErrInternalServerError,
+		},
+		{
+			desc:     "Run retried when rejected before processing",
+			path:     runPath,
+			codes:    []int{503, 429, 200},
+			attempts: 3,
+			delays:   []time.Duration{time.Second, 3 * time.Second},
+			want:     nil,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var n int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := atomic.AddInt32(&n, 1) - 1
+				if tC.retryAfter != "" {
+					w.Header().Set("Retry-After", tC.retryAfter)
+				}
+				w.WriteHeader(tC.codes[i])
+
+				switch tC.path {
+				case modelsPath:
+					w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+				case runPath:
+					w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+				default:
+					w.Write([]byte(`{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`))
+				}
+			}))
+			defer ts.Close()
+
+			c := &fakeClock{now: time.Unix(0, 0)}
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithRetryPolicy(policy), WithClock(c))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			switch tC.path {
+			case modelsPath:
+				_, err = k.SetModel()
+			case runPath:
+				k.Model = Model{ID: 4}
+				_, err = k.GetImageUUID(params)
+			default:
+				_, err = k.CheckImage(&UUID{ID: "test-uuid"})
+			}
+
+			if !errors.Is(err, tC.want) {
+				t.Fatalf("\n%s:\n\twant:\n\t\t\"%v\" \n\tgot:\n\t\t\"%v\"\n", tC.desc, tC.want, err)
+			}
+
+			if got := atomic.LoadInt32(&n); got != tC.attempts {
+				t.Errorf("\n%s:\n\twant attempts:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, tC.attempts, got)
+			}
+
+			if !reflect.DeepEqual(c.delays, tC.delays) {
+				t.Errorf("\n%s:\n\twant delays:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, tC.delays, c.delays)
+			}
+		})
+	}
+}
+
+// TestIsRetryable common test
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{desc: "Nil", err: nil, want: false},
+		{desc: "Too many requests", err: &APIError{StatusCode: 429}, want: true},
+		{desc: "Service unavailable", err: &APIError{StatusCode: 503}, want: true},
+		{desc: "Not implemented", err: &APIError{StatusCode: 501}, want: false},
+		{desc: "Unauthorized", err: &APIError{StatusCode: 401}, want: false},
+		{desc: "Wrapped server error", err: fmt.Errorf("wrap: %w", &APIError{StatusCode: 500}), want: true},
+		{desc: "Connection dropped", err: io.ErrUnexpectedEOF, want: true},
+		{desc: "Network error", err: &net.OpError{Op: "read", Err: errors.New("connection reset")}, want: true},
+		{desc: "Context canceled", err: context.Canceled, want: false},
+		{desc: "Censored", err: ErrCensored, want: false},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := IsRetryable(tC.err); got != tC.want {
+				t.Errorf("\n%s:\n\twant:\n\t\t%t \n\tgot:\n\t\t%t\n", tC.desc, tC.want, got)
+			}
+		})
+	}
+}
+
+// TestRetryContextDone checks that ctx error is returned when ctx is done during backoff
+func TestRetryContextDone(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = k.CheckImageContext(ctx, &UUID{ID: "test-uuid"})
+	var pe *PollError
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &pe) {
+		t.Errorf("\nwant:\n\t\t\"%s\" \n\tgot:\n\t\t\"%v\"\n", context.DeadlineExceeded, err)
+	}
+}
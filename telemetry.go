@@ -0,0 +1,142 @@
+package kandinsky
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// InstrumentationName is the name of tracer and meter used by the client.
+const InstrumentationName = "github.com/alekslesik/kandinsky"
+
+// Final statuses of tasks recorded by kandinsky.completions metric
+const (
+	// Task is done and image is not censored
+	CompletionDone = "DONE"
+	// Task failed
+	CompletionFail = "FAIL"
+	// Task is done but image is censored
+	CompletionCensored = "CENSORED"
+)
+
+// WithTracerProvider makes client emit spans for SetModel, GetImageUUID and every status request.
+// Without it no spans are emitted.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(k *Kand) {
+		k.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider makes client record metrics:
+//
+//	kandinsky.submissions          counter of submitted tasks
+//	kandinsky.completions          counter of finished tasks by final status: DONE, FAIL or CENSORED
+//	kandinsky.polls                counter of status requests by received status
+//	kandinsky.status.duration      histogram of seconds task spent in status, e.g. INITIAL is queueing
+//	kandinsky.generation.duration  histogram of seconds from submission to final status
+//
+// Without it no metrics are recorded.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(k *Kand) {
+		k.meterProvider = mp
+	}
+}
+
+// telemetry holds tracer and metric instruments
+type telemetry struct {
+	tracer trace.Tracer
+
+	submissions    metric.Int64Counter
+	completions    metric.Int64Counter
+	polls          metric.Int64Counter
+	statusDuration metric.Float64Histogram
+	duration       metric.Float64Histogram
+}
+
+// noTelemetry is used by Kand created without New
+var noTelemetry, _ = newTelemetry(nil, nil)
+
+// newTelemetry creates instruments, nil providers mean no-op ones
+func newTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) (*telemetry, error) {
+	if tp == nil {
+		tp = tracenoop.NewTracerProvider()
+	}
+	if mp == nil {
+		mp = metricnoop.NewMeterProvider()
+	}
+
+	t := &telemetry{tracer: tp.Tracer(InstrumentationName)}
+	m := mp.Meter(InstrumentationName)
+
+	var err error
+	t.submissions, err = m.Int64Counter("kandinsky.submissions",
+		metric.WithDescription("Number of submitted generation tasks."), metric.WithUnit("{task}"))
+	if err != nil {
+		return nil, err
+	}
+
+	t.completions, err = m.Int64Counter("kandinsky.completions",
+		metric.WithDescription("Number of finished generation tasks by final status."), metric.WithUnit("{task}"))
+	if err != nil {
+		return nil, err
+	}
+
+	t.polls, err = m.Int64Counter("kandinsky.polls",
+		metric.WithDescription("Number of task status requests."), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+
+	t.statusDuration, err = m.Float64Histogram("kandinsky.status.duration",
+		metric.WithDescription("Time task spent in status."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	t.duration, err = m.Float64Histogram("kandinsky.generation.duration",
+		metric.WithDescription("Time from submission to final status of task."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// getTelemetry returns telemetry or no-op one if Kand was created without New
+func (k *Kand) getTelemetry() *telemetry {
+	if k.tel == nil {
+		return noTelemetry
+	}
+
+	return k.tel
+}
+
+// start starts span name
+func (t *telemetry) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records err and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// completed records final status of task at now and its duration, unless submission time is unknown
+func (t *telemetry) completed(ctx context.Context, status string, submitted, now time.Time) {
+	attrs := metric.WithAttributes(attribute.String("status", status))
+	t.completions.Add(ctx, 1, attrs)
+	if !submitted.IsZero() {
+		t.duration.Record(ctx, now.Sub(submitted).Seconds(), attrs)
+	}
+}
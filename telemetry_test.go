@@ -0,0 +1,206 @@
+package kandinsky
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// counts returns sum of counter or count of histogram name by "status" attribute
+func counts(t *testing.T, rm metricdata.ResourceMetrics, name string) map[string]int64 {
+	t.Helper()
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					s, _ := dp.Attributes.Value(attribute.Key("status"))
+					got[s.AsString()] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					s, _ := dp.Attributes.Value(attribute.Key("status"))
+					got[s.AsString()] += int64(dp.Count)
+				}
+			}
+		}
+	}
+	return got
+}
+
+// TestTelemetry common test
+func TestTelemetry(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		final  string
+		polled string
+		status string
+	}{
+		{
+			desc:   "Done",
+			final:  `{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="]}`,
+			polled: "DONE",
+			status: CompletionDone,
+		},
+		{
+			desc:   "Censored",
+			final:  `{"uuid":"test-uuid","status":"DONE","images":["aGVsbG8="],"censored":true}`,
+			polled: "DONE",
+			status: CompletionCensored,
+		},
+		{
+			desc:   "Failed",
+			final:  `{"uuid":"test-uuid","status":"FAIL"}`,
+			polled: "FAIL",
+			status: CompletionFail,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// INITIAL, PROCESSING, then final status
+			var polls int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case modelsPath:
+					w.Write([]byte(`[{"id":4,"name":"Kandinsky","version":3.0,"type":"TEXT2IMAGE"}]`))
+				case runPath:
+					w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+				default:
+					switch atomic.AddInt32(&polls, 1) {
+					case 1:
+						w.Write([]byte(`{"uuid":"test-uuid","status":"INITIAL"}`))
+					case 2:
+						w.Write([]byte(`{"uuid":"test-uuid","status":"PROCESSING"}`))
+					default:
+						w.Write([]byte(tC.final))
+					}
+				}
+			}))
+			defer ts.Close()
+
+			spans := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+			reader := sdkmetric.NewManualReader()
+			mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+			k, err := New("key", "secret", WithBaseURL(ts.URL), WithTracerProvider(tp), WithMeterProvider(mp),
+				WithPollPolicy(PollPolicy{Interval: time.Millisecond}))
+			if err != nil {
+				t.Fatalf("create Kandinsky instance error > %s", err)
+			}
+
+			k.GetImage(params)
+
+			names := map[string]int{}
+			for _, s := range spans.Ended() {
+				names[s.Name()]++
+			}
+			want := map[string]int{"kandinsky.SetModel": 1, "kandinsky.GetImageUUID": 1, "kandinsky.CheckImage": 3}
+			for name, n := range want {
+				if names[name] != n {
+					t.Errorf("\n%s:\n\twant %s spans:\n\t\t%d \n\tgot:\n\t\t%d\n", tC.desc, name, n, names[name])
+				}
+			}
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("collect metrics error > %s", err)
+			}
+
+			metrics := []struct {
+				name string
+				want map[string]int64
+			}{
+				{"kandinsky.submissions", map[string]int64{"": 1}},
+				{"kandinsky.completions", map[string]int64{tC.status: 1}},
+				{"kandinsky.polls", map[string]int64{"INITIAL": 1, "PROCESSING": 1, tC.polled: 1}},
+				{"kandinsky.status.duration", map[string]int64{"INITIAL": 1, "PROCESSING": 1}},
+				{"kandinsky.generation.duration", map[string]int64{tC.status: 1}},
+			}
+			for _, m := range metrics {
+				got := counts(t, rm, m.name)
+				if len(got) != len(m.want) {
+					t.Errorf("\n%s:\n\twant %s:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, m.name, m.want, got)
+					continue
+				}
+				for status, n := range m.want {
+					if got[status] != n {
+						t.Errorf("\n%s:\n\twant %s:\n\t\t%v \n\tgot:\n\t\t%v\n", tC.desc, m.name, m.want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestTelemetryResumed checks generation duration of resumed task and span parent with shared poller
+func TestTelemetryResumed(t *testing.T) {
+	ts := (&fakeAPI{}).start(t)
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	// task submitted 10 minutes before restart
+	c := &fakeClock{now: time.Unix(600, 0)}
+	s := NewMemoryJobStore()
+	s.Put(context.Background(), JobRecord{UUID: "test-uuid", Params: params, ModelID: 4, SubmittedAt: time.Unix(0, 0)})
+
+	k, err := New("key", "secret", WithBaseURL(ts.URL), WithClock(c), WithJobStore(s),
+		WithSharedPoller(SharedPollerOptions{}), WithTracerProvider(tp), WithMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("create Kandinsky instance error > %s", err)
+	}
+	<-k.ResumedJobs()[0].Done()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect metrics error > %s", err)
+	}
+	if got := counts(t, rm, "kandinsky.generation.duration"); got[CompletionDone] != 1 {
+		t.Errorf("\nwant generation duration recorded once, got:\n\t\t%v\n", got)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "kandinsky.generation.duration" {
+				continue
+			}
+			dp := m.Data.(metricdata.Histogram[float64]).DataPoints
+			if len(dp) != 1 || dp[0].Sum != 600 {
+				t.Errorf("\nwant generation duration:\n\t\t600 \n\tgot:\n\t\t%+v\n", dp)
+			}
+		}
+	}
+
+	// shared status request is child of caller span
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if _, err := k.CheckImageContext(ctx, &UUID{ID: "test-uuid"}); err != nil {
+		t.Fatalf("check image error > %s", err)
+	}
+	parent.End()
+
+	var checked bool
+	for _, s := range spans.Ended() {
+		if s.Name() == "kandinsky.CheckImage" && s.Parent().SpanID() == parent.SpanContext().SpanID() {
+			checked = true
+		}
+	}
+	if !checked {
+		t.Errorf("\nwant kandinsky.CheckImage span child of caller span\n")
+	}
+}